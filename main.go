@@ -10,27 +10,66 @@ import (
 	"time"
 
 	"github.com/caarlos0/env/v9"
+	"github.com/dskvich/chatgpt-telegram-bot/pkg/anthropic"
+	"github.com/dskvich/chatgpt-telegram-bot/pkg/billing"
 	"github.com/dskvich/chatgpt-telegram-bot/pkg/converter"
 	"github.com/dskvich/chatgpt-telegram-bot/pkg/database"
 	"github.com/dskvich/chatgpt-telegram-bot/pkg/domain"
+	"github.com/dskvich/chatgpt-telegram-bot/pkg/gemini"
+	"github.com/dskvich/chatgpt-telegram-bot/pkg/httpclient"
+	"github.com/dskvich/chatgpt-telegram-bot/pkg/knowledge"
+	"github.com/dskvich/chatgpt-telegram-bot/pkg/llm"
 	"github.com/dskvich/chatgpt-telegram-bot/pkg/logger"
+	"github.com/dskvich/chatgpt-telegram-bot/pkg/memory"
 	"github.com/dskvich/chatgpt-telegram-bot/pkg/openai"
 	"github.com/dskvich/chatgpt-telegram-bot/pkg/repository"
+	"github.com/dskvich/chatgpt-telegram-bot/pkg/telegram/files"
 	"github.com/dskvich/chatgpt-telegram-bot/pkg/telegram/handlers"
 	"github.com/dskvich/chatgpt-telegram-bot/pkg/telegram/matchers"
 	"github.com/dskvich/chatgpt-telegram-bot/pkg/telegram/middleware"
+	"github.com/dskvich/chatgpt-telegram-bot/pkg/tools"
+	"github.com/dskvich/chatgpt-telegram-bot/pkg/transcription"
 	"github.com/dskvich/chatgpt-telegram-bot/pkg/workers"
 	"github.com/go-telegram/bot"
 )
 
+// telegramPollTimeout is how long getUpdates waits for new updates.
+const telegramPollTimeout = time.Minute
+
 type Config struct {
-	OpenAIToken                           string        `env:"OPEN_AI_TOKEN,required"`
-	TelegramBotToken                      string        `env:"TELEGRAM_BOT_TOKEN,required"`
-	TelegramAuthorizedUserIDs             []int64       `env:"TELEGRAM_AUTHORIZED_USER_IDS" envSeparator:" "`
-	TelegramUpdateListenerPoolSize        int           `env:"TELEGRAM_UPDATE_LISTENER_POOL_SIZE" envDefault:"10"`
-	TelegramUpdateListenerPollingInterval time.Duration `env:"TELEGRAM_UPDATE_LISTENER_POLL_INTERVAL" envDefault:"100ms"`
-	PgURL                                 string        `env:"DATABASE_URL"`
-	PgHost                                string        `env:"DB_HOST" envDefault:"localhost:65432"`
+	OpenAIToken                           string            `env:"OPEN_AI_TOKEN,required"`
+	OpenAIBaseURL                         string            `env:"OPEN_AI_BASE_URL"`
+	OpenAIChatBaseURL                     string            `env:"OPEN_AI_CHAT_BASE_URL"`
+	OpenAIAudioBaseURL                    string            `env:"OPEN_AI_AUDIO_BASE_URL"`
+	OpenAIImageBaseURL                    string            `env:"OPEN_AI_IMAGE_BASE_URL"`
+	OpenAIHeaders                         map[string]string `env:"OPEN_AI_HEADERS"`
+	OpenAIMaxRetries                      int               `env:"OPEN_AI_MAX_RETRIES" envDefault:"3"`
+	OpenAIToolsEnabled                    bool              `env:"OPEN_AI_TOOLS_ENABLED" envDefault:"true"`
+	OpenAIModelsAllow                     []string          `env:"OPEN_AI_MODELS_ALLOW" envSeparator:" " envDefault:"gpt-* o1* o3* o4* chatgpt-*"`
+	OpenAIModelsDeny                      []string          `env:"OPEN_AI_MODELS_DENY" envSeparator:" " envDefault:"*audio* *realtime* *transcribe* *tts* *search* *instruct* *image* *-pro* *deep-research*"`
+	OpenAIModelsRefreshInterval           time.Duration     `env:"OPEN_AI_MODELS_REFRESH_INTERVAL" envDefault:"1h"`
+	OpenAIConnectTimeout                  time.Duration     `env:"OPEN_AI_CONNECT_TIMEOUT" envDefault:"10s"`
+	OpenAIResponseHeaderTimeout           time.Duration     `env:"OPEN_AI_RESPONSE_HEADER_TIMEOUT" envDefault:"3m"`
+	OpenAIProxyURL                        string            `env:"OPEN_AI_PROXY_URL"`
+	MemoryEnabled                         bool              `env:"MEMORY_ENABLED" envDefault:"false"`
+	ModerationPolicy                      string            `env:"MODERATION_POLICY" envDefault:"off"`
+	AnthropicToken                        string            `env:"ANTHROPIC_TOKEN"`
+	AnthropicModels                       []string          `env:"ANTHROPIC_MODELS" envSeparator:" " envDefault:"claude-3-5-haiku-latest claude-3-7-sonnet-latest"`
+	GeminiToken                           string            `env:"GEMINI_TOKEN"`
+	GeminiModels                          []string          `env:"GEMINI_MODELS" envSeparator:" " envDefault:"gemini-2.0-flash gemini-2.0-flash-lite"`
+	TelegramBotToken                      string            `env:"TELEGRAM_BOT_TOKEN,required"`
+	TelegramAuthorizedUserIDs             []int64           `env:"TELEGRAM_AUTHORIZED_USER_IDS" envSeparator:" "`
+	TelegramAdminUserIDs                  []int64           `env:"TELEGRAM_ADMIN_USER_IDS" envSeparator:" "`
+	TelegramUpdateListenerPoolSize        int               `env:"TELEGRAM_UPDATE_LISTENER_POOL_SIZE" envDefault:"10"`
+	TelegramUpdateListenerPollingInterval time.Duration     `env:"TELEGRAM_UPDATE_LISTENER_POLL_INTERVAL" envDefault:"100ms"`
+	TelegramConnectTimeout                time.Duration     `env:"TELEGRAM_CONNECT_TIMEOUT" envDefault:"10s"`
+	TelegramResponseHeaderTimeout         time.Duration     `env:"TELEGRAM_RESPONSE_HEADER_TIMEOUT" envDefault:"75s"`
+	TelegramTimeout                       time.Duration     `env:"TELEGRAM_TIMEOUT" envDefault:"2m"`
+	TelegramProxyURL                      string            `env:"TELEGRAM_PROXY_URL"`
+	TelegramUpdateTimeout                 time.Duration     `env:"TELEGRAM_UPDATE_TIMEOUT" envDefault:"10m"`
+	PriceTablePath                        string            `env:"PRICE_TABLE_PATH"`
+	PgURL                                 string            `env:"DATABASE_URL"`
+	PgHost                                string            `env:"DB_HOST" envDefault:"localhost:65432"`
 }
 
 func main() {
@@ -80,24 +119,111 @@ func setupWorkers() (workers.Group, error) {
 		return nil, fmt.Errorf("creating db: %w", err)
 	}
 
-	openAIClient, err := openai.NewClient(cfg.OpenAIToken)
+	// No overall timeout: answers are streamed for as long as the model writes, and the deadline
+	// of the update bounds the whole request.
+	aiHTTPClient, err := httpclient.New(httpclient.Config{
+		ConnectTimeout:        cfg.OpenAIConnectTimeout,
+		ResponseHeaderTimeout: cfg.OpenAIResponseHeaderTimeout,
+		ProxyURL:              cfg.OpenAIProxyURL,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("creating open ai http client: %w", err)
+	}
+
+	// Long polling holds getUpdates open for telegramPollTimeout, the timeouts have to outlast it.
+	for _, timeout := range []time.Duration{cfg.TelegramResponseHeaderTimeout, cfg.TelegramTimeout} {
+		if timeout > 0 && timeout <= telegramPollTimeout {
+			return nil, fmt.Errorf("telegram timeouts must be longer than the polling timeout of %s", telegramPollTimeout)
+		}
+	}
+
+	telegramHTTPClient, err := httpclient.New(httpclient.Config{
+		ConnectTimeout:        cfg.TelegramConnectTimeout,
+		ResponseHeaderTimeout: cfg.TelegramResponseHeaderTimeout,
+		Timeout:               cfg.TelegramTimeout,
+		ProxyURL:              cfg.TelegramProxyURL,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("creating telegram http client: %w", err)
+	}
+
+	var toolExecutor openai.ToolExecutor
+	if cfg.OpenAIToolsEnabled {
+		toolExecutor = tools.NewRegistry(tools.Calculator{}, tools.Clock{}, tools.UnitConverter{})
+	}
+
+	openAIClient, err := openai.NewClient(openai.Config{
+		Token:        cfg.OpenAIToken,
+		BaseURL:      cfg.OpenAIBaseURL,
+		ChatBaseURL:  cfg.OpenAIChatBaseURL,
+		AudioBaseURL: cfg.OpenAIAudioBaseURL,
+		ImageBaseURL: cfg.OpenAIImageBaseURL,
+		Headers:      cfg.OpenAIHeaders,
+		MaxRetries:   cfg.OpenAIMaxRetries,
+		Tools:        toolExecutor,
+		HTTPClient:   aiHTTPClient,
+	})
 	if err != nil {
 		return nil, fmt.Errorf("creating open ai client: %w", err)
 	}
 
 	chatRepository := repository.NewChatRepository()
 	stateRepository := repository.NewStateRepository()
+	imageRepository := repository.NewImageRepository()
+	alternativesRepository := repository.NewAlternativesRepository()
 	promptRepository := repository.NewPromptsRepository(db)
 	settingsRepository := repository.NewSettingsRepository(db)
+	usageRepository := repository.NewUsageRepository(db)
+	budgetsRepository := repository.NewBudgetsRepository(db)
+	moderationRepository := repository.NewModerationRepository(db)
+	memoriesRepository := repository.NewMemoriesRepository(db)
+	knowledgeBaseRepository := repository.NewKnowledgeBaseRepository(db)
+
+	prices, err := billing.LoadPrices(cfg.PriceTablePath)
+	if err != nil {
+		return nil, fmt.Errorf("loading prices: %w", err)
+	}
+	usageRecorder := billing.NewRecorder(prices, usageRepository)
 
-	// Price per 1M tokens (Input/Output)
-	// https://platform.openai.com/docs/pricing
+	knowledgeBase := knowledge.NewBase(openAIClient, knowledgeBaseRepository)
+	documentExtractor := &converter.DocumentToText{}
+
+	var memoryKeeper handlers.MemoryKeeper
+	if cfg.MemoryEnabled {
+		memoryKeeper = memory.NewKeeper(openAIClient, openAIClient, memoriesRepository, usageRecorder)
+	}
+
+	// Used until the models are fetched from the API, or when discovery is off.
 	supportedTextModels := []string{
-		"gpt-4o-mini",   // $0.15/$0.60
-		"gpt-3.5-turbo", // $0.50/$1.50
-		"o3-mini",       // $1.10/$4.40
-		// "gpt-4o",        // $2.50/$10.00
-		// "gpt-4-turbo",   // $10.00/$30.00
+		"gpt-4o-mini",
+		"gpt-3.5-turbo",
+		"o3-mini",
+	}
+
+	textModelRegistry := llm.NewRegistry()
+	textModelRegistry.Register(openAIClient, supportedTextModels...)
+
+	if cfg.AnthropicToken != "" {
+		anthropicClient, err := anthropic.NewClient(cfg.AnthropicToken, aiHTTPClient)
+		if err != nil {
+			return nil, fmt.Errorf("creating anthropic client: %w", err)
+		}
+		textModelRegistry.Register(anthropicClient, cfg.AnthropicModels...)
+	}
+
+	if cfg.GeminiToken != "" {
+		geminiClient, err := gemini.NewClient(cfg.GeminiToken, aiHTTPClient)
+		if err != nil {
+			return nil, fmt.Errorf("creating gemini client: %w", err)
+		}
+		textModelRegistry.Register(geminiClient, cfg.GeminiModels...)
+	}
+
+	moderationPolicy := domain.ModerationPolicy(cfg.ModerationPolicy)
+	switch moderationPolicy {
+	case domain.ModerationPolicyOff, domain.ModerationPolicyWarn, domain.ModerationPolicyBlock:
+	default:
+		return nil, fmt.Errorf("unsupported moderation policy: %s", cfg.ModerationPolicy)
 	}
 
 	supportedTTLOptions := []time.Duration{
@@ -108,26 +234,96 @@ func setupWorkers() (workers.Group, error) {
 		7 * 24 * time.Hour,
 	}
 
+	supportedHistoryDepthOptions := []int{0, 5, 10, 20, 50}
+
+	supportedAlternativesOptions := []int{1, 2, 3, 4}
+
+	supportedImageModels := []domain.ImageModel{domain.DallE2, domain.DallE3}
+
+	supportedReasoningEfforts := []domain.ReasoningEffort{
+		"",
+		domain.ReasoningEffortLow,
+		domain.ReasoningEffortMedium,
+		domain.ReasoningEffortHigh,
+	}
+
+	supportedTranslationLanguages := []string{"English", "Русский", "Deutsch", "Español", "Français", "Italiano", "Português", "中文", "日本語"}
+
+	supportedVoices := []string{"alloy", "ash", "coral", "echo", "fable", "onyx", "nova", "sage", "shimmer"}
+	speechConverter := &converter.SpeechToVoice{}
+	audioConverter := &converter.VoiceToMP3{}
+	chunkedTranscriber := transcription.NewChunked(audioConverter, openAIClient)
+
+	fileDownloader := files.NewDownloader(telegramHTTPClient)
+
+	knowledgeBaseHandler := handlers.ManageKnowledgeBase(knowledgeBase, documentExtractor, fileDownloader, usageRecorder)
+
 	opts := []bot.Option{
+		bot.WithHTTPClient(telegramPollTimeout, telegramHTTPClient),
 		bot.WithMiddlewares(
 			middleware.RequestID,
+			middleware.Timeout(cfg.TelegramUpdateTimeout),
 			middleware.Auth(cfg.TelegramAuthorizedUserIDs),
+			middleware.Budget(budgetsRepository, usageRepository),
 			middleware.Typing,
-			middleware.VoiceToText(&converter.VoiceToMP3{}, openAIClient),
+			middleware.VoiceToText(audioConverter, openAIClient, chunkedTranscriber, settingsRepository, fileDownloader, usageRecorder),
+			middleware.Moderation(openAIClient, moderationRepository, moderationPolicy),
 		),
 
-		bot.WithDefaultHandler(handlers.GenerateContent(settingsRepository, chatRepository, promptRepository, openAIClient)),
+		bot.WithDefaultHandler(handlers.GenerateContent(
+			settingsRepository,
+			chatRepository,
+			promptRepository,
+			openAIClient,
+			textModelRegistry,
+			imageRepository,
+			alternativesRepository,
+			memoryKeeper,
+			documentExtractor,
+			openAIClient,
+			knowledgeBase,
+			openAIClient,
+			speechConverter,
+			fileDownloader,
+			usageRecorder,
+		)),
 		bot.WithMessageTextHandler("/start", bot.MatchTypePrefix, handlers.Start()),
-		bot.WithMessageTextHandler("/new", bot.MatchTypePrefix, handlers.ClearChat(chatRepository)),
-		bot.WithMessageTextHandler("/text_models", bot.MatchTypePrefix, handlers.ShowTextModels(supportedTextModels)),
-		bot.WithMessageTextHandler("/image_models", bot.MatchTypePrefix, handlers.ShowImageModels()),
+		bot.WithMessageTextHandler("/new", bot.MatchTypePrefix, handlers.ClearChat(chatRepository, memoryKeeper)),
+		bot.WithMessageTextHandler("/text_models", bot.MatchTypePrefix, handlers.ShowTextModels(textModelRegistry)),
+		bot.WithMessageTextHandler("/image_models", bot.MatchTypePrefix, handlers.ShowImageModels(supportedImageModels)),
 		bot.WithMessageTextHandler("/system_prompt", bot.MatchTypePrefix, handlers.ShowSystemPrompt(settingsRepository)),
 		bot.WithMessageTextHandler("/ttl", bot.MatchTypePrefix, handlers.ShowTTL(supportedTTLOptions)),
+		bot.WithMessageTextHandler("/history", bot.MatchTypePrefix, handlers.ShowHistoryDepth(supportedHistoryDepthOptions)),
+		bot.WithMessageTextHandler("/reasoning", bot.MatchTypePrefix, handlers.ShowReasoningEffort(supportedReasoningEfforts)),
+		bot.WithMessageTextHandler("/params", bot.MatchTypePrefix, handlers.ShowParams(settingsRepository)),
+		bot.WithMessageTextHandler("/drafts", bot.MatchTypePrefix, handlers.ShowAlternatives(supportedAlternativesOptions)),
+		bot.WithMessageTextHandler("/memories", bot.MatchTypePrefix, handlers.ManageMemories(memoriesRepository)),
+		bot.WithMessageTextHandler("/kb", bot.MatchTypePrefix, knowledgeBaseHandler),
+		bot.WithMessageTextHandler("/translate", bot.MatchTypePrefix, handlers.ShowTranslation(settingsRepository, supportedTranslationLanguages)),
+		bot.WithMessageTextHandler("/voice", bot.MatchTypePrefix, handlers.ShowVoices(supportedVoices)),
+		bot.WithMessageTextHandler("/usage", bot.MatchTypePrefix, handlers.ShowUsage(usageRepository)),
+		bot.WithMessageTextHandler("/moderation", bot.MatchTypePrefix, handlers.ManageModeration(moderationRepository, moderationPolicy, cfg.TelegramAdminUserIDs)),
+		bot.WithMessageTextHandler("/budget", bot.MatchTypePrefix, handlers.ManageBudget(budgetsRepository, usageRepository, cfg.TelegramAdminUserIDs)),
 
 		bot.WithCallbackQueryDataHandler(domain.SetTTLCallbackPrefix, bot.MatchTypePrefix, handlers.SetTTL(settingsRepository, supportedTTLOptions)),
-		bot.WithCallbackQueryDataHandler(domain.SetTextModelCallbackPrefix, bot.MatchTypePrefix, handlers.SetTextModel(settingsRepository, chatRepository, supportedTextModels)),
+		bot.WithCallbackQueryDataHandler(domain.SetHistoryDepthCallbackPrefix, bot.MatchTypePrefix, handlers.SetHistoryDepth(settingsRepository, supportedHistoryDepthOptions)),
+		bot.WithCallbackQueryDataHandler(domain.SetTextModelCallbackPrefix, bot.MatchTypePrefix, handlers.SetTextModel(settingsRepository, chatRepository, textModelRegistry)),
+		bot.WithCallbackQueryDataHandler(domain.TextModelsPageCallbackPrefix, bot.MatchTypePrefix, handlers.ShowTextModelsPage(textModelRegistry)),
+		bot.WithCallbackQueryDataHandler(domain.SetImageModelCallbackPrefix, bot.MatchTypePrefix, handlers.SetImageModel(settingsRepository, supportedImageModels)),
+		bot.WithCallbackQueryDataHandler(domain.SetImageSizeCallbackPrefix, bot.MatchTypePrefix, handlers.SetImageSize(settingsRepository)),
+		bot.WithCallbackQueryDataHandler(domain.SetImageQualityCallbackPrefix, bot.MatchTypePrefix, handlers.SetImageQuality(settingsRepository)),
+		bot.WithCallbackQueryDataHandler(domain.SetReasoningCallbackPrefix, bot.MatchTypePrefix, handlers.SetReasoningEffort(settingsRepository, supportedReasoningEfforts)),
+		bot.WithCallbackQueryDataHandler(domain.SetParamCallbackPrefix, bot.MatchTypePrefix, handlers.SetParam(settingsRepository)),
+		bot.WithCallbackQueryDataHandler(domain.SetAlternativesCallbackPrefix, bot.MatchTypePrefix, handlers.SetAlternatives(settingsRepository, supportedAlternativesOptions)),
+		bot.WithCallbackQueryDataHandler(domain.PickAlternativeCallbackPrefix, bot.MatchTypePrefix, handlers.PickAlternative(chatRepository, alternativesRepository)),
+		bot.WithCallbackQueryDataHandler(domain.DeleteMemoryCallbackPrefix, bot.MatchTypePrefix, handlers.DeleteMemory(memoriesRepository)),
+		bot.WithCallbackQueryDataHandler(domain.SetTranslateCallbackPrefix, bot.MatchTypePrefix, handlers.SetTranslation(settingsRepository, supportedTranslationLanguages)),
+		bot.WithCallbackQueryDataHandler(domain.SetVoiceTranslationCallbackPrefix, bot.MatchTypePrefix, handlers.SetVoiceTranslation(settingsRepository, supportedTranslationLanguages)),
+		bot.WithCallbackQueryDataHandler(domain.SetVoiceCallbackPrefix, bot.MatchTypePrefix, handlers.SetVoice(settingsRepository, supportedVoices)),
+		bot.WithCallbackQueryDataHandler(domain.SpeakCallbackPrefix, bot.MatchTypePrefix, handlers.SpeakText(settingsRepository, openAIClient, speechConverter, usageRecorder)),
 		bot.WithCallbackQueryDataHandler(domain.SetSystemPromptCallbackPrefix, bot.MatchTypePrefix, handlers.RequestSystemPrompt(stateRepository)),
-		bot.WithCallbackQueryDataHandler(domain.GenImageCallbackPrefix, bot.MatchTypePrefix, handlers.RegenerateImage(promptRepository, openAIClient)),
+		bot.WithCallbackQueryDataHandler(domain.GenImageCallbackPrefix, bot.MatchTypePrefix, handlers.RegenerateImage(settingsRepository, promptRepository, openAIClient, imageRepository, usageRecorder)),
+		bot.WithCallbackQueryDataHandler(domain.RefineImageCallbackPrefix, bot.MatchTypePrefix, handlers.RequestImageRefinement(stateRepository)),
 	}
 
 	b, err := bot.New(cfg.TelegramBotToken, opts...)
@@ -137,6 +333,20 @@ func setupWorkers() (workers.Group, error) {
 
 	b.RegisterHandlerMatchFunc(matchers.IsEditingSystemPrompt(stateRepository), handlers.SetSystemPrompt(settingsRepository, chatRepository, stateRepository))
 
+	editImageHandler := handlers.EditImage(settingsRepository, stateRepository, imageRepository, openAIClient, fileDownloader, usageRecorder)
+	b.RegisterHandlerMatchFunc(matchers.IsRefiningImage(stateRepository), editImageHandler)
+	b.RegisterHandlerMatchFunc(matchers.IsImageEdit(), editImageHandler)
+	b.RegisterHandlerMatchFunc(matchers.IsKnowledgeBaseUpload(), knowledgeBaseHandler)
+
+	if cfg.OpenAIModelsRefreshInterval > 0 {
+		if worker, err = workers.NewModelDiscovery(openAIClient, textModelRegistry, cfg.OpenAIModelsAllow, cfg.OpenAIModelsDeny,
+			cfg.OpenAIModelsRefreshInterval); err == nil {
+			workerGroup = append(workerGroup, worker)
+		} else {
+			return nil, err
+		}
+	}
+
 	if worker, err = workers.NewTelegramBot(b); err == nil {
 		workerGroup = append(workerGroup, worker)
 	} else {
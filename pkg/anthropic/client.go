@@ -0,0 +1,218 @@
+package anthropic
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/dskvich/chatgpt-telegram-bot/pkg/domain"
+	"github.com/dskvich/chatgpt-telegram-bot/pkg/sse"
+	"github.com/samber/lo"
+)
+
+const (
+	apiURLMessages = "https://api.anthropic.com/v1/messages"
+	apiVersion     = "2023-06-01"
+
+	defaultMaxTokens = 4096
+	maxTemperature   = 1.0 // OpenAI allows up to 2
+)
+
+type client struct {
+	token string
+	hc    *http.Client
+}
+
+func NewClient(token string, hc *http.Client) (*client, error) {
+	if token == "" {
+		return nil, errors.New("token cannot be empty")
+	}
+	return &client{
+		token: token,
+		hc:    lo.CoalesceOrEmpty(hc, &http.Client{}),
+	}, nil
+}
+
+func (c *client) CreateChatCompletion(ctx context.Context, chat *domain.Chat) (*domain.Completion, error) {
+	msgReq, err := newMessagesRequest(chat)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.send(ctx, msgReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send messages request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var parsedResp messagesResponse
+	if err := json.NewDecoder(resp.Body).Decode(&parsedResp); err != nil {
+		return nil, fmt.Errorf("failed to parse messages response: %w", err)
+	}
+
+	var content strings.Builder
+	for _, block := range parsedResp.Content {
+		if block.Type == contentBlockTypeText {
+			content.WriteString(block.Text)
+		}
+	}
+
+	if content.Len() == 0 {
+		return nil, errors.New("no text content returned in response")
+	}
+
+	var tokens domain.Usage
+	if parsedResp.Usage != nil {
+		tokens = domain.Usage{PromptTokens: parsedResp.Usage.InputTokens, CompletionTokens: parsedResp.Usage.OutputTokens}
+	}
+
+	return &domain.Completion{
+		Message: domain.Message{
+			Role:         parsedResp.Role,
+			ContentParts: []domain.ContentPart{{Type: domain.ContentPartTypeText, Data: content.String()}},
+		},
+		Usage: tokens,
+	}, nil
+}
+
+func (c *client) StreamChatCompletion(ctx context.Context, chat *domain.Chat, onDelta func(delta string)) (*domain.Completion, error) {
+	msgReq, err := newMessagesRequest(chat)
+	if err != nil {
+		return nil, err
+	}
+	msgReq.Stream = true
+
+	resp, err := c.send(ctx, msgReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send messages request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var (
+		content strings.Builder
+		tokens  domain.Usage
+	)
+
+	err = sse.Read(resp.Body, func(event sse.Event) error {
+		var parsed streamEvent
+		if err := json.Unmarshal([]byte(event.Data), &parsed); err != nil {
+			return fmt.Errorf("failed to parse stream event: %w", err)
+		}
+
+		switch parsed.Type {
+		case streamEventMessageStart:
+			if parsed.Message != nil && parsed.Message.Usage != nil {
+				tokens.PromptTokens = parsed.Message.Usage.InputTokens
+			}
+		case streamEventMessageDelta:
+			if parsed.Usage != nil {
+				tokens.CompletionTokens = parsed.Usage.OutputTokens
+			}
+		case streamEventContentBlockDelta:
+			if parsed.Delta != nil && parsed.Delta.Type == streamDeltaTypeText && parsed.Delta.Text != "" {
+				content.WriteString(parsed.Delta.Text)
+				onDelta(parsed.Delta.Text)
+			}
+		case streamEventMessageStop:
+			return io.EOF
+		case streamEventError:
+			if parsed.Error != nil {
+				return fmt.Errorf("stream error: %s: %s", parsed.Error.Type, parsed.Error.Message)
+			}
+			return errors.New("stream error")
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read messages stream: %w", err)
+	}
+
+	if content.Len() == 0 {
+		return nil, errors.New("no content returned in stream")
+	}
+
+	return &domain.Completion{
+		Message: domain.Message{
+			Role:         domain.MessageRoleAssistant,
+			ContentParts: []domain.ContentPart{{Type: domain.ContentPartTypeText, Data: content.String()}},
+		},
+		Usage: tokens,
+	}, nil
+}
+
+func (c *client) send(ctx context.Context, msgReq *messagesRequest) (*http.Response, error) {
+	reqBody, err := json.Marshal(msgReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, apiURLMessages, bytes.NewBuffer(reqBody))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create HTTP request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Api-Key", c.token)
+	req.Header.Set("Anthropic-Version", apiVersion)
+
+	resp, err := c.hc.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("HTTP request failed: %w", err)
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		defer resp.Body.Close()
+		respBody, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("unexpected status code: %d, response: %s", resp.StatusCode, string(respBody))
+	}
+
+	return resp, nil
+}
+
+func newMessagesRequest(chat *domain.Chat) (*messagesRequest, error) {
+	messages := make([]message, 0, len(chat.Messages))
+
+	for _, msg := range chat.Messages {
+		if msg.Role == domain.MessageRoleTool || len(msg.ToolCalls) > 0 {
+			continue // tool calls of other providers are not replayed
+		}
+
+		blocks := make([]contentBlock, 0, len(msg.ContentParts))
+		for _, content := range msg.ContentParts {
+			switch content.Type {
+			case domain.ContentPartTypeText:
+				blocks = append(blocks, contentBlock{Type: contentBlockTypeText, Text: content.Data})
+			case domain.ContentPartTypeImage:
+				blocks = append(blocks, contentBlock{Type: contentBlockTypeImage, Source: newImageSource(content)})
+			default:
+				return nil, errors.New("unsupported content type")
+			}
+		}
+		messages = append(messages, message{Role: msg.Role, Content: blocks})
+	}
+
+	var temperature *float64
+	if chat.Params.Temperature != nil {
+		temperature = lo.ToPtr(min(*chat.Params.Temperature, maxTemperature))
+	}
+
+	return &messagesRequest{
+		Model:       chat.Model,
+		System:      chat.SystemPrompt,
+		Messages:    messages,
+		MaxTokens:   lo.CoalesceOrEmpty(chat.Params.MaxTokens, defaultMaxTokens),
+		Temperature: temperature,
+		TopP:        chat.Params.TopP,
+	}, nil
+}
+
+func newImageSource(part domain.ContentPart) *imageSource {
+	if mediaType, data, ok := part.InlineImage(); ok {
+		return &imageSource{Type: imageSourceTypeBase64, MediaType: mediaType, Data: data}
+	}
+	return &imageSource{Type: imageSourceTypeURL, URL: part.Data}
+}
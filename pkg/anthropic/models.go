@@ -0,0 +1,81 @@
+package anthropic
+
+type messagesRequest struct {
+	Model       string    `json:"model"`
+	System      string    `json:"system,omitempty"`
+	Messages    []message `json:"messages"`
+	MaxTokens   int       `json:"max_tokens"`
+	Temperature *float64  `json:"temperature,omitempty"`
+	TopP        *float64  `json:"top_p,omitempty"`
+	Stream      bool      `json:"stream,omitempty"`
+}
+
+type messagesResponse struct {
+	Role    string         `json:"role"`
+	Content []contentBlock `json:"content"`
+	Usage   *usage         `json:"usage,omitempty"`
+}
+
+type usage struct {
+	InputTokens  int `json:"input_tokens"`
+	OutputTokens int `json:"output_tokens"`
+}
+
+type message struct {
+	Role    string         `json:"role"`
+	Content []contentBlock `json:"content"`
+}
+
+type contentBlockType string
+
+const (
+	contentBlockTypeText  contentBlockType = "text"
+	contentBlockTypeImage contentBlockType = "image"
+)
+
+type contentBlock struct {
+	Type   contentBlockType `json:"type"`
+	Text   string           `json:"text,omitempty"`
+	Source *imageSource     `json:"source,omitempty"`
+}
+
+type imageSourceType string
+
+const (
+	imageSourceTypeBase64 imageSourceType = "base64"
+	imageSourceTypeURL    imageSourceType = "url"
+)
+
+type imageSource struct {
+	Type      imageSourceType `json:"type"`
+	MediaType string          `json:"media_type,omitempty"`
+	Data      string          `json:"data,omitempty"`
+	URL       string          `json:"url,omitempty"`
+}
+
+type streamEvent struct {
+	Type    string            `json:"type"`
+	Message *messagesResponse `json:"message,omitempty"`
+	Delta   *streamDelta      `json:"delta,omitempty"`
+	Usage   *usage            `json:"usage,omitempty"`
+	Error   *apiError         `json:"error,omitempty"`
+}
+
+type streamDelta struct {
+	Type string `json:"type"`
+	Text string `json:"text,omitempty"`
+}
+
+type apiError struct {
+	Type    string `json:"type"`
+	Message string `json:"message"`
+}
+
+const (
+	streamEventMessageStart      = "message_start"
+	streamEventMessageDelta      = "message_delta"
+	streamEventContentBlockDelta = "content_block_delta"
+	streamEventMessageStop       = "message_stop"
+	streamEventError             = "error"
+	streamDeltaTypeText          = "text_delta"
+)
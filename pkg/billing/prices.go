@@ -0,0 +1,166 @@
+package billing
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"maps"
+	"os"
+	"sync"
+
+	"github.com/dskvich/chatgpt-telegram-bot/pkg/domain"
+	"github.com/dskvich/chatgpt-telegram-bot/pkg/llm"
+)
+
+const (
+	tokensPerPriceUnit = 1_000_000
+	secondsPerMinute   = 60
+	charsPerPriceUnit  = 1_000_000
+)
+
+// TextPrice is the price in USD per 1M tokens.
+type TextPrice struct {
+	Input  float64 `json:"input"`
+	Output float64 `json:"output"`
+}
+
+// unpricedModels remembers the models already reported as missing from the price table.
+var unpricedModels sync.Map
+
+// Prices is the price table in USD. Text prices are keyed by model name prefixes and matched
+// by the longest one, so dated snapshots share the price of their model. Image prices are per
+// image, keyed by size, or by "size/quality" for anything but standard quality. Audio prices
+// are per minute, speech prices are per 1M characters.
+type Prices struct {
+	Text   map[string]TextPrice          `json:"text"`
+	Image  map[string]map[string]float64 `json:"image"`
+	Audio  map[string]float64            `json:"audio"`
+	Speech map[string]float64            `json:"speech"`
+}
+
+// DefaultPrices returns the list prices from https://platform.openai.com/docs/pricing,
+// https://www.anthropic.com/pricing and https://ai.google.dev/pricing.
+func DefaultPrices() Prices {
+	return Prices{
+		Text: map[string]TextPrice{
+			"gpt-4o-mini":            {Input: 0.15, Output: 0.60},
+			"gpt-3.5-turbo":          {Input: 0.50, Output: 1.50},
+			"o1":                     {Input: 15.00, Output: 60.00},
+			"o1-mini":                {Input: 1.10, Output: 4.40},
+			"o1-preview":             {Input: 15.00, Output: 60.00},
+			"o3":                     {Input: 2.00, Output: 8.00},
+			"o3-mini":                {Input: 1.10, Output: 4.40},
+			"o4-mini":                {Input: 1.10, Output: 4.40},
+			"gpt-4o":                 {Input: 2.50, Output: 10.00},
+			"chatgpt-4o":             {Input: 5.00, Output: 15.00},
+			"gpt-4":                  {Input: 30.00, Output: 60.00},
+			"gpt-4-turbo":            {Input: 10.00, Output: 30.00},
+			"gpt-4.1":                {Input: 2.00, Output: 8.00},
+			"gpt-4.1-mini":           {Input: 0.40, Output: 1.60},
+			"gpt-4.1-nano":           {Input: 0.10, Output: 0.40},
+			"gpt-4.5":                {Input: 75.00, Output: 150.00},
+			"gpt-5":                  {Input: 1.25, Output: 10.00},
+			"gpt-5-mini":             {Input: 0.25, Output: 2.00},
+			"gpt-5-nano":             {Input: 0.05, Output: 0.40},
+			"claude-3-5-haiku":       {Input: 0.80, Output: 4.00},
+			"claude-3-7-sonnet":      {Input: 3.00, Output: 15.00},
+			"gemini-2.0-flash":       {Input: 0.10, Output: 0.40},
+			"gemini-2.0-flash-lite":  {Input: 0.075, Output: 0.30},
+			"text-embedding-3-small": {Input: 0.02},
+		},
+		Image: map[string]map[string]float64{
+			string(domain.DallE2): {
+				string(domain.Size256x256):   0.016,
+				string(domain.Size512x512):   0.018,
+				string(domain.Size1024x1024): 0.020,
+			},
+			string(domain.DallE3): {
+				string(domain.Size1024x1024):                     0.040,
+				string(domain.Size1024x1792):                     0.080,
+				string(domain.Size1792x1024):                     0.080,
+				imageKey(domain.Size1024x1024, domain.QualityHD): 0.080,
+				imageKey(domain.Size1024x1792, domain.QualityHD): 0.120,
+				imageKey(domain.Size1792x1024, domain.QualityHD): 0.120,
+			},
+		},
+		Audio: map[string]float64{
+			domain.Whisper1Model: 0.006,
+		},
+		Speech: map[string]float64{
+			domain.TTS1Model: 15.00,
+		},
+	}
+}
+
+// LoadPrices reads a JSON price table and lays it over the defaults, so the file only needs
+// the models whose prices differ or are missing. An empty path returns the defaults.
+func LoadPrices(path string) (Prices, error) {
+	prices := DefaultPrices()
+	if path == "" {
+		return prices, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Prices{}, fmt.Errorf("reading price table: %w", err)
+	}
+
+	var custom Prices
+	if err := json.Unmarshal(data, &custom); err != nil {
+		return Prices{}, fmt.Errorf("parsing price table: %w", err)
+	}
+
+	maps.Copy(prices.Text, custom.Text)
+	maps.Copy(prices.Audio, custom.Audio)
+	maps.Copy(prices.Speech, custom.Speech)
+	for model, sizes := range custom.Image {
+		if prices.Image[model] == nil {
+			prices.Image[model] = make(map[string]float64)
+		}
+		maps.Copy(prices.Image[model], sizes)
+	}
+
+	return prices, nil
+}
+
+// Cost returns the price of the record in USD. Usage of models missing from the table is free,
+// a warning is logged the first time such a model is used.
+func (p Prices) Cost(record domain.UsageRecord) float64 {
+	var cost float64
+
+	if tokens := record.Usage.PromptTokens + record.Usage.CompletionTokens; tokens > 0 {
+		if price, ok := llm.LongestPrefix(p.Text, record.Model); ok {
+			cost += float64(record.Usage.PromptTokens) * price.Input / tokensPerPriceUnit
+			cost += float64(record.Usage.CompletionTokens) * price.Output / tokensPerPriceUnit
+		} else {
+			warnUnpriced(record.Model)
+		}
+	}
+
+	if record.Usage.Images > 0 {
+		cost += float64(record.Usage.Images) * p.Image[record.Model][imageKey(record.ImageSize, record.ImageQuality)]
+	}
+
+	if record.Usage.AudioSeconds > 0 {
+		cost += float64(record.Usage.AudioSeconds) * p.Audio[record.Model] / secondsPerMinute
+	}
+
+	if record.Usage.SpeechCharacters > 0 {
+		cost += float64(record.Usage.SpeechCharacters) * p.Speech[record.Model] / charsPerPriceUnit
+	}
+
+	return cost
+}
+
+func warnUnpriced(model string) {
+	if _, reported := unpricedModels.LoadOrStore(model, struct{}{}); !reported {
+		slog.Warn("Model is missing from the price table, its usage is not counted in budgets", "model", model)
+	}
+}
+
+func imageKey(size domain.ImageSize, quality domain.ImageQuality) string {
+	if quality == "" || quality == domain.QualityStandard {
+		return string(size)
+	}
+	return string(size) + "/" + string(quality)
+}
@@ -0,0 +1,26 @@
+package billing
+
+import (
+	"context"
+
+	"github.com/dskvich/chatgpt-telegram-bot/pkg/domain"
+)
+
+type usageSaver interface {
+	Save(ctx context.Context, record domain.UsageRecord) error
+}
+
+// recorder prices usage records before they are stored.
+type recorder struct {
+	prices Prices
+	saver  usageSaver
+}
+
+func NewRecorder(prices Prices, saver usageSaver) *recorder {
+	return &recorder{prices: prices, saver: saver}
+}
+
+func (r *recorder) Save(ctx context.Context, record domain.UsageRecord) error {
+	record.Usage.Cost = r.prices.Cost(record)
+	return r.saver.Save(ctx, record)
+}
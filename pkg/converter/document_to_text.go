@@ -0,0 +1,161 @@
+package converter
+
+import (
+	"archive/zip"
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/dskvich/chatgpt-telegram-bot/pkg/domain"
+)
+
+// textExtensions are read as plain text: documents, data formats and source code.
+var textExtensions = []string{
+	".txt", ".md", ".markdown", ".rst", ".log", ".csv", ".tsv", ".json", ".yaml", ".yml", ".toml", ".ini",
+	".xml", ".html", ".htm", ".css", ".sql", ".sh", ".bash", ".zsh", ".ps1", ".bat", ".dockerfile",
+	".go", ".py", ".js", ".jsx", ".ts", ".tsx", ".java", ".kt", ".kts", ".scala", ".groovy", ".swift", ".m",
+	".c", ".h", ".cc", ".cpp", ".hpp", ".cs", ".rs", ".rb", ".php", ".pl", ".lua", ".r", ".dart", ".ex",
+	".exs", ".erl", ".hs", ".clj", ".vue", ".svelte", ".proto", ".graphql", ".tf", ".gradle", ".mod",
+}
+
+const (
+	mimeTypePDF  = "application/pdf"
+	mimeTypeDOCX = "application/vnd.openxmlformats-officedocument.wordprocessingml.document"
+
+	// maxDocxBodySize bounds the unpacked body of a DOCX file, which a crafted archive may inflate
+	// far beyond its download size.
+	maxDocxBodySize = 100 << 20
+)
+
+type DocumentToText struct{}
+
+// ExtractText returns the text of a plain text, source code, PDF or DOCX file. Other files
+// yield domain.ErrUnsupportedDocument. PDF text is extracted with `pdftotext` from poppler.
+func (d *DocumentToText) ExtractText(ctx context.Context, fileName, mimeType string, data []byte) (string, error) {
+	ext := strings.ToLower(filepath.Ext(fileName))
+
+	switch {
+	case ext == ".pdf" || mimeType == mimeTypePDF:
+		return d.pdfText(ctx, data)
+	case ext == ".docx" || mimeType == mimeTypeDOCX:
+		return docxText(data)
+	case slices.Contains(textExtensions, ext) || strings.HasPrefix(mimeType, "text/"):
+		if bytes.IndexByte(data, 0) >= 0 {
+			return "", fmt.Errorf("%w: binary content", domain.ErrUnsupportedDocument)
+		}
+		return strings.ToValidUTF8(string(data), "�"), nil
+	default:
+		return "", fmt.Errorf("%w: %s", domain.ErrUnsupportedDocument, cmp.Or(ext, mimeType))
+	}
+}
+
+func (d *DocumentToText) pdfText(ctx context.Context, data []byte) (string, error) {
+	const (
+		documentTempDir      = "tmp/documents"
+		documentTempFilePerm = 0o644
+	)
+
+	if _, err := exec.LookPath("pdftotext"); err != nil {
+		return "", fmt.Errorf("looking for `pdftotext`: %w", err)
+	}
+
+	if err := os.MkdirAll(documentTempDir, os.ModePerm); err != nil {
+		return "", fmt.Errorf("unable to create temp directory: %w", err)
+	}
+
+	pdfPath := filepath.Join(documentTempDir, fmt.Sprintf("document-%d.pdf", time.Now().UnixNano()))
+	if err := os.WriteFile(pdfPath, data, documentTempFilePerm); err != nil {
+		return "", fmt.Errorf("unable to write pdf file: %w", err)
+	}
+	defer os.Remove(pdfPath)
+
+	slog.InfoContext(ctx, "Extracting text from pdf...", "inputPath", pdfPath)
+
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "pdftotext", "-enc", "UTF-8", pdfPath, "-")
+	cmd.Stderr = &stderr
+
+	output, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("running `pdftotext`: %w: %s", err, stderr.String())
+	}
+
+	return string(output), nil
+}
+
+// docxText reads the paragraphs of word/document.xml, the body of a DOCX file.
+func docxText(data []byte) (string, error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return "", fmt.Errorf("opening docx: %w", err)
+	}
+
+	body := slices.IndexFunc(zr.File, func(f *zip.File) bool { return f.Name == "word/document.xml" })
+	if body < 0 {
+		return "", errors.New("opening docx body: no word/document.xml")
+	}
+	if size := zr.File[body].UncompressedSize64; size > maxDocxBodySize {
+		return "", fmt.Errorf("docx body is too large: %d bytes, at most %d", size, maxDocxBodySize)
+	}
+
+	rc, err := zr.File[body].Open()
+	if err != nil {
+		return "", fmt.Errorf("opening docx body: %w", err)
+	}
+	defer rc.Close()
+
+	// The declared size may lie, the reader stops at the limit either way.
+	f := io.LimitReader(rc, maxDocxBodySize)
+
+	var (
+		sb     strings.Builder
+		inText bool
+	)
+
+	dec := xml.NewDecoder(f)
+	for {
+		tok, err := dec.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return "", fmt.Errorf("parsing docx body: %w", err)
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "t":
+				inText = true
+			case "tab":
+				sb.WriteByte('\t')
+			case "br", "cr":
+				sb.WriteByte('\n')
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "t":
+				inText = false
+			case "p":
+				sb.WriteByte('\n')
+			}
+		case xml.CharData:
+			if inText {
+				sb.Write(t)
+			}
+		}
+	}
+
+	return sb.String(), nil
+}
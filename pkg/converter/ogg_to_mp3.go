@@ -1,50 +1,208 @@
 package converter
 
 import (
+	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"log/slog"
+	"math"
+	"os"
 	"os/exec"
-	"path"
-
-	"golang.org/x/net/context"
+	"regexp"
+	"strconv"
+	"strings"
 )
 
+// decodedTimeRe matches the progress ffmpeg reports while decoding, e.g. time=00:12:34.56.
+var decodedTimeRe = regexp.MustCompile(`time=(\d+):(\d{2}):(\d{2}(?:\.\d+)?)`)
+
 type VoiceToMP3 struct{}
 
+// mediaInfo describes a media file as reported by ffprobe.
+type mediaInfo struct {
+	audioCodec string // codec of the first audio stream, empty without audio
+	hasVideo   bool
+	seconds    float64
+}
+
+// ConvertToMP3 extracts the audio track of a voice note, audio or video file into MP3.
+// The container is probed first: MP3 audio is copied as is, anything else is re-encoded
+// and video streams are dropped.
 func (v *VoiceToMP3) ConvertToMP3(ctx context.Context, inputPath string) (string, error) {
-	slog.InfoContext(ctx, "Converting voice message to mp3...", "inputPath", inputPath)
-
-	var (
-		outputPath string
-		err        error
-	)
-	if path.Ext(inputPath) == ".ogg" || path.Ext(inputPath) == ".oga" {
-		outputPath, err = v.convertAudioToMp3(inputPath)
-		if err != nil {
-			return "", fmt.Errorf("converting file: %w", err)
-		}
+	slog.InfoContext(ctx, "Converting media to mp3...", "inputPath", inputPath)
+
+	info, err := probeMedia(ctx, inputPath)
+	if err != nil {
+		return "", err
+	}
+
+	if info.audioCodec == "" {
+		return "", errors.New("no audio stream in the file")
+	}
+
+	if _, err := exec.LookPath("ffmpeg"); err != nil {
+		return "", fmt.Errorf("looking for `ffmpeg`: %w", err)
+	}
+
+	outputPath := inputPath + ".mp3"
+
+	args := []string{"-y", "-i", inputPath, "-map", "0:a:0", "-vn"}
+	if info.audioCodec == "mp3" {
+		args = append(args, "-c:a", "copy")
 	} else {
-		return "", errors.New("invalid voice message format")
+		args = append(args, "-c:a", "libmp3lame")
 	}
+	args = append(args, outputPath)
 
-	slog.InfoContext(ctx, "Conversion successful", "inputPath", inputPath, "outputPath", outputPath)
+	cmd := exec.CommandContext(ctx, "ffmpeg", args...)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return outputPath, fmt.Errorf("running `ffmpeg`: %w: %s", err, output)
+	}
 
-	return outputPath, err
+	slog.InfoContext(ctx, "Conversion successful", "inputPath", inputPath, "outputPath", outputPath,
+		"audioCodec", info.audioCodec, "hasVideo", info.hasVideo)
+
+	return outputPath, nil
 }
 
-func (v *VoiceToMP3) convertAudioToMp3(filePath string) (string, error) {
+// SplitAudio cuts the audio track of a media file into MP3 segments of segmentSeconds that overlap
+// by overlapSeconds, so that words cut at a boundary are heard whole in one of the segments.
+// The segments are mono 64 kbps, which keeps even long ones far below the upload limit of Whisper.
+// The caller removes the returned files.
+func (v *VoiceToMP3) SplitAudio(ctx context.Context, inputPath string, segmentSeconds, overlapSeconds int) ([]string, error) {
+	if segmentSeconds <= overlapSeconds {
+		return nil, fmt.Errorf("segment of %ds must be longer than the overlap of %ds", segmentSeconds, overlapSeconds)
+	}
+
+	info, err := probeMedia(ctx, inputPath)
+	if err != nil {
+		return nil, err
+	}
+
+	if info.audioCodec == "" {
+		return nil, errors.New("no audio stream in the file")
+	}
+
 	if _, err := exec.LookPath("ffmpeg"); err != nil {
-		return "", fmt.Errorf("looking for `ffmpeg`: %w", err)
+		return nil, fmt.Errorf("looking for `ffmpeg`: %w", err)
+	}
+
+	// Streamed recordings may not store their length, without it the end of the audio is unknown.
+	if info.seconds <= 0 {
+		if info.seconds, err = decodedSeconds(ctx, inputPath); err != nil {
+			return nil, err
+		}
+	}
+
+	slog.InfoContext(ctx, "Splitting media into segments...", "inputPath", inputPath, "seconds", info.seconds,
+		"segmentSeconds", segmentSeconds, "overlapSeconds", overlapSeconds)
+
+	var segments []string
+	step := segmentSeconds - overlapSeconds
+
+	for start := 0; start == 0 || float64(start+overlapSeconds) < info.seconds; start += step {
+		outputPath := fmt.Sprintf("%s.part%03d.mp3", inputPath, len(segments))
+
+		cmd := exec.CommandContext(ctx, "ffmpeg", "-y",
+			"-ss", strconv.Itoa(start), "-t", strconv.Itoa(segmentSeconds), "-i", inputPath,
+			"-map", "0:a:0", "-vn", "-ac", "1", "-c:a", "libmp3lame", "-b:a", "64k", outputPath)
+		if output, err := cmd.CombinedOutput(); err != nil {
+			for _, segment := range append(segments, outputPath) {
+				os.Remove(segment)
+			}
+			return nil, fmt.Errorf("running `ffmpeg` for the segment at %ds: %w: %s", start, err, output)
+		}
+
+		segments = append(segments, outputPath)
 	}
 
-	newFilePath := filePath + ".mp3"
+	slog.InfoContext(ctx, "Splitting successful", "inputPath", inputPath, "segments", len(segments))
+
+	return segments, nil
+}
 
-	cmd := exec.Command("ffmpeg", "-i", filePath, newFilePath)
-	_, err := cmd.CombinedOutput()
+// Duration returns the length of a media file in whole seconds, rounded up.
+func (v *VoiceToMP3) Duration(ctx context.Context, inputPath string) (int, error) {
+	info, err := probeMedia(ctx, inputPath)
 	if err != nil {
-		return newFilePath, fmt.Errorf("running `ffmpeg`: %w", err)
+		return 0, err
+	}
+	return int(math.Ceil(info.seconds)), nil
+}
+
+// decodedSeconds measures the length of the audio track by decoding it, for files whose container
+// does not tell it.
+func decodedSeconds(ctx context.Context, inputPath string) (float64, error) {
+	cmd := exec.CommandContext(ctx, "ffmpeg", "-i", inputPath, "-map", "0:a:0", "-f", "null", "-")
+
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return 0, fmt.Errorf("running `ffmpeg` to measure the duration: %w: %s", err, output)
+	}
+
+	matches := decodedTimeRe.FindAllStringSubmatch(string(output), -1)
+	if len(matches) == 0 {
+		return 0, errors.New("unknown duration of the audio")
+	}
+
+	last := matches[len(matches)-1]
+	hours, _ := strconv.Atoi(last[1])
+	minutes, _ := strconv.Atoi(last[2])
+	seconds, _ := strconv.ParseFloat(last[3], 64)
+
+	total := float64(hours*3600+minutes*60) + seconds
+	if total <= 0 {
+		return 0, errors.New("unknown duration of the audio")
+	}
+
+	return total, nil
+}
+
+func probeMedia(ctx context.Context, inputPath string) (*mediaInfo, error) {
+	if _, err := exec.LookPath("ffprobe"); err != nil {
+		return nil, fmt.Errorf("looking for `ffprobe`: %w", err)
+	}
+
+	cmd := exec.CommandContext(ctx, "ffprobe", "-v", "error",
+		"-show_entries", "stream=codec_type,codec_name:format=duration", "-of", "json", inputPath)
+
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("running `ffprobe`: %w", err)
+	}
+
+	var probe struct {
+		Streams []struct {
+			CodecType string `json:"codec_type"`
+			CodecName string `json:"codec_name"`
+		} `json:"streams"`
+		Format struct {
+			Duration string `json:"duration"`
+		} `json:"format"`
+	}
+
+	if err := json.Unmarshal(output, &probe); err != nil {
+		return nil, fmt.Errorf("parsing `ffprobe` output: %w", err)
+	}
+
+	info := &mediaInfo{}
+	for _, s := range probe.Streams {
+		switch s.CodecType {
+		case "audio":
+			if info.audioCodec == "" {
+				info.audioCodec = strings.ToLower(s.CodecName)
+			}
+		case "video":
+			info.hasVideo = true
+		}
+	}
+
+	if probe.Format.Duration != "" {
+		if info.seconds, err = strconv.ParseFloat(probe.Format.Duration, 64); err != nil {
+			return nil, fmt.Errorf("parsing duration %q: %w", probe.Format.Duration, err)
+		}
 	}
 
-	return newFilePath, nil
+	return info, nil
 }
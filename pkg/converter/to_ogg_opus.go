@@ -0,0 +1,31 @@
+package converter
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os/exec"
+	"strings"
+)
+
+type SpeechToVoice struct{}
+
+// ConvertToOggOpus converts an audio file into OGG/Opus, the format Telegram plays as a voice note.
+func (s *SpeechToVoice) ConvertToOggOpus(ctx context.Context, inputPath string) (string, error) {
+	slog.InfoContext(ctx, "Converting speech to ogg/opus...", "inputPath", inputPath)
+
+	if _, err := exec.LookPath("ffmpeg"); err != nil {
+		return "", fmt.Errorf("looking for `ffmpeg`: %w", err)
+	}
+
+	outputPath := strings.TrimSuffix(inputPath, ".mp3") + ".ogg"
+
+	cmd := exec.CommandContext(ctx, "ffmpeg", "-y", "-i", inputPath, "-c:a", "libopus", "-b:a", "32k", outputPath)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return outputPath, fmt.Errorf("running `ffmpeg`: %w: %s", err, output)
+	}
+
+	slog.InfoContext(ctx, "Conversion successful", "inputPath", inputPath, "outputPath", outputPath)
+
+	return outputPath, nil
+}
@@ -0,0 +1,16 @@
+-- +migrate Up
+CREATE TABLE usage_records (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL,
+    chat_id BIGINT NOT NULL,
+    topic_id INTEGER NOT NULL,
+    model VARCHAR NOT NULL,
+    prompt_tokens INTEGER NOT NULL DEFAULT 0,
+    completion_tokens INTEGER NOT NULL DEFAULT 0,
+    images INTEGER NOT NULL DEFAULT 0,
+    transcriptions INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX usage_records_user_id_created_at_idx ON usage_records (user_id, created_at);
+CREATE INDEX usage_records_chat_id_topic_id_created_at_idx ON usage_records (chat_id, topic_id, created_at);
\ No newline at end of file
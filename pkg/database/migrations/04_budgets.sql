@@ -0,0 +1,12 @@
+-- +migrate Up
+ALTER TABLE usage_records
+    ADD COLUMN audio_seconds INTEGER NOT NULL DEFAULT 0,
+    ADD COLUMN cost NUMERIC(14, 6) NOT NULL DEFAULT 0;
+
+CREATE TABLE budgets (
+    scope VARCHAR NOT NULL,
+    subject_id BIGINT NOT NULL,
+    period VARCHAR NOT NULL,
+    limit_usd NUMERIC(14, 6) NOT NULL,
+    PRIMARY KEY (scope, subject_id, period)
+);
\ No newline at end of file
@@ -0,0 +1,3 @@
+-- +migrate Up
+ALTER TABLE settings
+    ADD COLUMN max_history_depth INTEGER NOT NULL DEFAULT 0;
\ No newline at end of file
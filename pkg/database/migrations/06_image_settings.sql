@@ -0,0 +1,3 @@
+-- +migrate Up
+ALTER TABLE settings ADD COLUMN image_size VARCHAR NOT NULL DEFAULT '';
+ALTER TABLE settings ADD COLUMN image_quality VARCHAR NOT NULL DEFAULT '';
\ No newline at end of file
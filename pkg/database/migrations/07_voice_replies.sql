@@ -0,0 +1,7 @@
+-- +migrate Up
+ALTER TABLE settings
+    ADD COLUMN voice_reply BOOLEAN NOT NULL DEFAULT false,
+    ADD COLUMN voice VARCHAR NOT NULL DEFAULT '';
+
+ALTER TABLE usage_records
+    ADD COLUMN speech_characters INTEGER NOT NULL DEFAULT 0;
\ No newline at end of file
@@ -0,0 +1,5 @@
+-- +migrate Up
+CREATE TABLE moderation_categories (
+    chat_id BIGINT PRIMARY KEY,
+    categories VARCHAR NOT NULL
+);
\ No newline at end of file
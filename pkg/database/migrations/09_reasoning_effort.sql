@@ -0,0 +1,2 @@
+-- +migrate Up
+ALTER TABLE settings ADD COLUMN reasoning_effort VARCHAR NOT NULL DEFAULT '';
\ No newline at end of file
@@ -0,0 +1,8 @@
+-- +migrate Up
+ALTER TABLE settings
+    ADD COLUMN temperature DOUBLE PRECISION,
+    ADD COLUMN top_p DOUBLE PRECISION,
+    ADD COLUMN presence_penalty DOUBLE PRECISION,
+    ADD COLUMN frequency_penalty DOUBLE PRECISION,
+    ADD COLUMN max_tokens INTEGER NOT NULL DEFAULT 0,
+    ADD COLUMN seed BIGINT;
\ No newline at end of file
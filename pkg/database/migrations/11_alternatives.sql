@@ -0,0 +1,3 @@
+-- +migrate Up
+ALTER TABLE settings
+    ADD COLUMN alternatives INTEGER NOT NULL DEFAULT 0;
\ No newline at end of file
@@ -0,0 +1,10 @@
+-- +migrate Up
+CREATE TABLE memories (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL,
+    content TEXT NOT NULL,
+    embedding BYTEA NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX memories_user_id_created_at_idx ON memories (user_id, created_at);
\ No newline at end of file
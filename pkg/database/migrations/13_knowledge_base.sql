@@ -0,0 +1,21 @@
+-- +migrate Up
+CREATE TABLE kb_documents (
+    id BIGSERIAL PRIMARY KEY,
+    chat_id BIGINT NOT NULL,
+    title VARCHAR NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX kb_documents_chat_id_idx ON kb_documents (chat_id);
+
+CREATE TABLE kb_chunks (
+    id BIGSERIAL PRIMARY KEY,
+    document_id BIGINT NOT NULL REFERENCES kb_documents (id) ON DELETE CASCADE,
+    chat_id BIGINT NOT NULL,
+    position INTEGER NOT NULL,
+    content TEXT NOT NULL,
+    embedding BYTEA NOT NULL
+);
+
+CREATE INDEX kb_chunks_chat_id_idx ON kb_chunks (chat_id);
+CREATE INDEX kb_chunks_document_id_idx ON kb_chunks (document_id);
\ No newline at end of file
@@ -0,0 +1,4 @@
+-- +migrate Up
+ALTER TABLE settings
+    ADD COLUMN voice_translation BOOLEAN NOT NULL DEFAULT FALSE,
+    ADD COLUMN translate_to VARCHAR NOT NULL DEFAULT '';
\ No newline at end of file
@@ -0,0 +1,13 @@
+package domain
+
+import "time"
+
+// Alternatives are answers waiting for the user to pick the one that goes into the chat.
+// Chat already holds the question; ChatUpdatedAt is when the stored chat was last saved before
+// the answers were generated, a newer chat means the alternatives are outdated.
+type Alternatives struct {
+	Chat          Chat
+	ChatUpdatedAt time.Time
+	Choices       []Message
+	MessageIDs    []int
+}
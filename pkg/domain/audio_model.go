@@ -0,0 +1,14 @@
+package domain
+
+const (
+	Whisper1Model = "whisper-1"
+	TTS1Model     = "tts-1"
+
+	DefaultVoice = "alloy"
+)
+
+// Transcript is the text recognized in a recording.
+type Transcript struct {
+	Text     string
+	Duration int // seconds of audio, rounded up
+}
@@ -0,0 +1,42 @@
+package domain
+
+import "time"
+
+type BudgetScope string
+
+const (
+	BudgetScopeUser BudgetScope = "user"
+	BudgetScopeChat BudgetScope = "chat"
+)
+
+type BudgetPeriod string
+
+const (
+	BudgetPeriodDay   BudgetPeriod = "day"
+	BudgetPeriodMonth BudgetPeriod = "month"
+)
+
+// Start returns the beginning of the period that contains t.
+func (p BudgetPeriod) Start(t time.Time) time.Time {
+	y, m, d := t.Date()
+	if p == BudgetPeriodMonth {
+		d = 1
+	}
+	return time.Date(y, m, d, 0, 0, 0, 0, t.Location())
+}
+
+// End returns the moment the period that contains t resets.
+func (p BudgetPeriod) End(t time.Time) time.Time {
+	if p == BudgetPeriodMonth {
+		return p.Start(t).AddDate(0, 1, 0)
+	}
+	return p.Start(t).AddDate(0, 0, 1)
+}
+
+// Budget limits spending of a user or a whole chat within a period.
+type Budget struct {
+	Scope     BudgetScope
+	SubjectID int64
+	Period    BudgetPeriod
+	Limit     float64 // USD
+}
@@ -1,9 +1,23 @@
 package domain
 
 const (
-	GenImageCallbackPrefix        = "genimg_"
-	SetTTLCallbackPrefix          = "ttl_"
-	SetTextModelCallbackPrefix    = "textmodel_"
-	SetImageModelCallbackPrefix   = "imgmodel_"
-	SetSystemPromptCallbackPrefix = "systemprompt_"
+	GenImageCallbackPrefix            = "genimg_"
+	RefineImageCallbackPrefix         = "refineimg_"
+	SetTTLCallbackPrefix              = "ttl_"
+	SetTextModelCallbackPrefix        = "textmodel_"
+	TextModelsPageCallbackPrefix      = "tmpage_"
+	SetImageModelCallbackPrefix       = "imgmodel_"
+	SetImageSizeCallbackPrefix        = "imgsize_"
+	SetImageQualityCallbackPrefix     = "imgquality_"
+	SetSystemPromptCallbackPrefix     = "systemprompt_"
+	SetHistoryDepthCallbackPrefix     = "histdepth_"
+	SetVoiceCallbackPrefix            = "voice_"
+	SpeakCallbackPrefix               = "speak_"
+	SetReasoningCallbackPrefix        = "reasoning_"
+	SetParamCallbackPrefix            = "param_"
+	SetAlternativesCallbackPrefix     = "alts_"
+	PickAlternativeCallbackPrefix     = "pick_"
+	DeleteMemoryCallbackPrefix        = "memdel_"
+	SetTranslateCallbackPrefix        = "translate_"
+	SetVoiceTranslationCallbackPrefix = "voicetr_"
 )
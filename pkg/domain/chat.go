@@ -1,6 +1,10 @@
 package domain
 
-import "time"
+import (
+	"encoding/json"
+	"strings"
+	"time"
+)
 
 type Chat struct {
 	ID           int64
@@ -8,15 +12,49 @@ type Chat struct {
 	Model        string
 	TTL          time.Duration
 	SystemPrompt string
-	Messages     []Message
+	// ReasoningEffort is sent to models that support it, empty means the model default.
+	ReasoningEffort ReasoningEffort
+	Params          GenerationParams
+	// Choices is how many alternative answers to request, values below 2 mean a single answer.
+	Choices  int
+	Messages []Message
+}
+
+// Completion is a model answer together with the tokens spent on it. Steps holds the tool calls
+// and tool results that led to the answer, in the order they have to be added to the chat.
+// When several choices were requested, Alternatives holds all of them and Message is the first one.
+type Completion struct {
+	Steps        []Message
+	Message      Message
+	Alternatives []Message
+	Usage        Usage
 }
 
 type Message struct {
 	Role         string
 	ContentParts []ContentPart
+	ToolCalls    []ToolCall // set on assistant messages that request tools
+	ToolCallID   string     // set on tool messages, refers to ToolCall.ID
+}
+
+const (
+	MessageRoleUser      = "user"
+	MessageRoleAssistant = "assistant"
+	MessageRoleTool      = "tool"
+)
+
+type ToolCall struct {
+	ID        string
+	Name      string
+	Arguments string // JSON object
 }
 
-const MessageRoleUser = "user"
+// ToolDefinition describes a tool to the model. Parameters is a JSON schema of the arguments.
+type ToolDefinition struct {
+	Name        string
+	Description string
+	Parameters  json.RawMessage
+}
 
 type ContentPart struct {
 	Type ContentPartType
@@ -29,3 +67,23 @@ const (
 	ContentPartTypeText  ContentPartType = "text"
 	ContentPartTypeImage ContentPartType = "image"
 )
+
+// InlineImage splits an image part stored as a base64 data URL into its media type and payload.
+func (p ContentPart) InlineImage() (string, string, bool) {
+	rest, ok := strings.CutPrefix(p.Data, "data:")
+	if !ok {
+		return "", "", false
+	}
+
+	meta, data, ok := strings.Cut(rest, ",")
+	if !ok {
+		return "", "", false
+	}
+
+	mediaType, ok := strings.CutSuffix(meta, ";base64")
+	if !ok {
+		return "", "", false
+	}
+
+	return mediaType, data, true
+}
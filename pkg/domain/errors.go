@@ -3,3 +3,16 @@ package domain
 import "errors"
 
 var ErrNotFound = errors.New("entity not found")
+
+// ErrUnsupportedDocument is returned for files the bot cannot read text from.
+var ErrUnsupportedDocument = errors.New("unsupported document type")
+
+// Failures reported by AI providers that the user can act on.
+var (
+	ErrRateLimited           = errors.New("rate limit exceeded")
+	ErrInsufficientQuota     = errors.New("insufficient quota")
+	ErrContextLengthExceeded = errors.New("context length exceeded")
+	ErrContentPolicy         = errors.New("content policy violation")
+	ErrInvalidModel          = errors.New("invalid model")
+	ErrUnauthorized          = errors.New("authentication failed")
+)
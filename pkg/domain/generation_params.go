@@ -0,0 +1,11 @@
+package domain
+
+// GenerationParams tune how the model samples its answer. Nil and zero values leave the model defaults.
+type GenerationParams struct {
+	Temperature      *float64
+	TopP             *float64
+	PresencePenalty  *float64
+	FrequencyPenalty *float64
+	MaxTokens        int
+	Seed             *int64
+}
@@ -1,5 +1,10 @@
 package domain
 
+import (
+	"slices"
+	"strings"
+)
+
 type ImageModel string
 
 const (
@@ -23,3 +28,65 @@ const (
 	QualityStandard ImageQuality = "standard"
 	QualityHD       ImageQuality = "hd"
 )
+
+// ImageSettings describes how an image is generated.
+type ImageSettings struct {
+	Model   ImageModel
+	Size    ImageSize
+	Quality ImageQuality
+}
+
+// ImageSizes returns the sizes the model can generate, the first one is the default.
+func ImageSizes(model ImageModel) []ImageSize {
+	switch model {
+	case DallE3:
+		return []ImageSize{Size1024x1024, Size1024x1792, Size1792x1024}
+	default:
+		return []ImageSize{Size256x256, Size512x512, Size1024x1024}
+	}
+}
+
+// ImageQualities returns the qualities the model supports, empty when it has no quality option.
+func ImageQualities(model ImageModel) []ImageQuality {
+	if model == DallE3 {
+		return []ImageQuality{QualityStandard, QualityHD}
+	}
+	return nil
+}
+
+// NewImageSettings replaces missing or unsupported values with the model defaults.
+func NewImageSettings(model ImageModel, size ImageSize, quality ImageQuality) ImageSettings {
+	if model != DallE3 {
+		model = DallE2
+	}
+
+	sizes := ImageSizes(model)
+	if !slices.Contains(sizes, size) {
+		size = sizes[0]
+	}
+
+	qualities := ImageQualities(model)
+	switch {
+	case len(qualities) == 0:
+		quality = ""
+	case !slices.Contains(qualities, quality):
+		quality = qualities[0]
+	}
+
+	return ImageSettings{Model: model, Size: size, Quality: quality}
+}
+
+// EditImageSettings adapts the settings for edits and variations, which only dall-e-2 supports.
+func EditImageSettings(settings ImageSettings) ImageSettings {
+	size := settings.Size
+	if !slices.Contains(ImageSizes(DallE2), size) {
+		size = Size1024x1024
+	}
+	return NewImageSettings(DallE2, size, "")
+}
+
+// IsImagePrompt reports whether the text asks to draw a picture.
+func IsImagePrompt(text string) bool {
+	text = strings.ToLower(text)
+	return strings.Contains(text, "рисуй") || strings.Contains(text, "draw")
+}
@@ -0,0 +1,22 @@
+package domain
+
+import "time"
+
+// KnowledgeDocument is a text added to the knowledge base of a chat. It is stored as chunks
+// that are retrieved for every question asked in the chat.
+type KnowledgeDocument struct {
+	ID        int64
+	ChatID    int64
+	Title     string
+	Chunks    int
+	CreatedAt time.Time
+}
+
+type KnowledgeChunk struct {
+	ID            int64
+	DocumentID    int64
+	DocumentTitle string
+	Position      int
+	Content       string
+	Embedding     []float32
+}
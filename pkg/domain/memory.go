@@ -0,0 +1,13 @@
+package domain
+
+import "time"
+
+// Memory is a fact about a user remembered from past conversations. Embedding is used to find
+// the memories relevant to a new conversation.
+type Memory struct {
+	ID        int64
+	UserID    int64
+	Content   string
+	Embedding []float32
+	CreatedAt time.Time
+}
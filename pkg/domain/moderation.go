@@ -0,0 +1,34 @@
+package domain
+
+// ModerationPolicy decides what happens to a prompt the moderation model flags.
+type ModerationPolicy string
+
+const (
+	ModerationPolicyOff   ModerationPolicy = "off"
+	ModerationPolicyWarn  ModerationPolicy = "warn"
+	ModerationPolicyBlock ModerationPolicy = "block"
+)
+
+// ModerationCategories are the categories reported by the moderations endpoint.
+var ModerationCategories = []string{
+	"harassment",
+	"harassment/threatening",
+	"hate",
+	"hate/threatening",
+	"illicit",
+	"illicit/violent",
+	"self-harm",
+	"self-harm/intent",
+	"self-harm/instructions",
+	"sexual",
+	"sexual/minors",
+	"violence",
+	"violence/graphic",
+}
+
+// ModerationResult is the verdict of the moderation model on a text.
+type ModerationResult struct {
+	Flagged    bool
+	Categories map[string]bool
+	Scores     map[string]float64
+}
@@ -8,5 +8,26 @@ type Settings struct {
 	TextModel    string
 	SystemPrompt string
 	ImageModel   string
+	ImageSize    ImageSize
+	ImageQuality ImageQuality
 	TTL          time.Duration
+	// VoiceReply makes the bot read every answer aloud with Voice.
+	VoiceReply      bool
+	Voice           string
+	ReasoningEffort ReasoningEffort
+	Params          GenerationParams
+	// VoiceTranslation turns speech into English text instead of a transcript.
+	VoiceTranslation bool
+	// TranslateTo makes the bot translate every message into the language instead of answering,
+	// empty means the usual chat.
+	TranslateTo string
+	// Alternatives is how many answers to offer for every message, values below 2 mean a single answer.
+	Alternatives int
+	// MaxHistoryDepth limits how many past turns are sent to the model, zero means no limit.
+	MaxHistoryDepth int
+}
+
+// ImageSettings returns the image generation settings with defaults applied.
+func (s *Settings) ImageSettings() ImageSettings {
+	return NewImageSettings(ImageModel(s.ImageModel), s.ImageSize, s.ImageQuality)
 }
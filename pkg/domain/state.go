@@ -4,4 +4,5 @@ type State int
 
 const (
 	StateEditSystemPrompt State = iota
+	StateRefineImage
 )
@@ -2,4 +2,15 @@ package domain
 
 const (
 	Gpt4oMiniModel = "gpt-4o-mini"
+
+	TextEmbedding3SmallModel = "text-embedding-3-small"
+)
+
+// ReasoningEffort tells reasoning models how much to think before answering.
+type ReasoningEffort string
+
+const (
+	ReasoningEffortLow    ReasoningEffort = "low"
+	ReasoningEffortMedium ReasoningEffort = "medium"
+	ReasoningEffortHigh   ReasoningEffort = "high"
 )
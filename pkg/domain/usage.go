@@ -0,0 +1,24 @@
+package domain
+
+import "time"
+
+type Usage struct {
+	PromptTokens     int
+	CompletionTokens int
+	Images           int
+	Transcriptions   int
+	AudioSeconds     int
+	SpeechCharacters int
+	Cost             float64 // USD
+}
+
+type UsageRecord struct {
+	UserID       int64
+	ChatID       int64
+	TopicID      int
+	Model        string
+	ImageSize    ImageSize
+	ImageQuality ImageQuality
+	Usage        Usage
+	CreatedAt    time.Time
+}
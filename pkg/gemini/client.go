@@ -0,0 +1,249 @@
+package gemini
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/dskvich/chatgpt-telegram-bot/pkg/domain"
+	"github.com/dskvich/chatgpt-telegram-bot/pkg/sse"
+	"github.com/samber/lo"
+)
+
+const (
+	apiURLModels = "https://generativelanguage.googleapis.com/v1beta/models/"
+
+	methodGenerateContent       = ":generateContent"
+	methodStreamGenerateContent = ":streamGenerateContent?alt=sse"
+
+	defaultMaxTokens = 4096
+)
+
+type client struct {
+	token string
+	hc    *http.Client
+}
+
+func NewClient(token string, hc *http.Client) (*client, error) {
+	if token == "" {
+		return nil, errors.New("token cannot be empty")
+	}
+	return &client{
+		token: token,
+		hc:    lo.CoalesceOrEmpty(hc, &http.Client{}),
+	}, nil
+}
+
+func (c *client) CreateChatCompletion(ctx context.Context, chat *domain.Chat) (*domain.Completion, error) {
+	genReq, err := newGenerateContentRequest(chat)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.send(ctx, chat.Model, methodGenerateContent, genReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send generate content request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var parsedResp generateContentResponse
+	if err := json.NewDecoder(resp.Body).Decode(&parsedResp); err != nil {
+		return nil, fmt.Errorf("failed to parse generate content response: %w", err)
+	}
+
+	text, err := responseText(&parsedResp)
+	if err != nil {
+		return nil, err
+	}
+
+	if text == "" {
+		return nil, errors.New("no text content returned in response")
+	}
+
+	completion := &domain.Completion{
+		Message: newAssistantMessage(text),
+		Usage:   parsedResp.UsageMetadata.toDomain(),
+	}
+
+	if len(parsedResp.Candidates) > 1 {
+		for _, c := range parsedResp.Candidates {
+			if text := candidateText(c); text != "" {
+				completion.Alternatives = append(completion.Alternatives, newAssistantMessage(text))
+			}
+		}
+	}
+
+	return completion, nil
+}
+
+func (c *client) StreamChatCompletion(ctx context.Context, chat *domain.Chat, onDelta func(delta string)) (*domain.Completion, error) {
+	genReq, err := newGenerateContentRequest(chat)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.send(ctx, chat.Model, methodStreamGenerateContent, genReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send generate content request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var (
+		content strings.Builder
+		tokens  domain.Usage
+	)
+
+	err = sse.Read(resp.Body, func(event sse.Event) error {
+		var chunk generateContentResponse
+		if err := json.Unmarshal([]byte(event.Data), &chunk); err != nil {
+			return fmt.Errorf("failed to parse generate content chunk: %w", err)
+		}
+
+		text, err := responseText(&chunk)
+		if err != nil {
+			return err
+		}
+
+		if chunk.UsageMetadata != nil {
+			tokens = chunk.UsageMetadata.toDomain() // counts are cumulative
+		}
+
+		if text != "" {
+			content.WriteString(text)
+			onDelta(text)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read generate content stream: %w", err)
+	}
+
+	if content.Len() == 0 {
+		return nil, errors.New("no content returned in stream")
+	}
+
+	return &domain.Completion{
+		Message: newAssistantMessage(content.String()),
+		Usage:   tokens,
+	}, nil
+}
+
+func newAssistantMessage(text string) domain.Message {
+	return domain.Message{
+		Role:         domain.MessageRoleAssistant,
+		ContentParts: []domain.ContentPart{{Type: domain.ContentPartTypeText, Data: text}},
+	}
+}
+
+func (c *client) send(ctx context.Context, model, method string, genReq *generateContentRequest) (*http.Response, error) {
+	reqBody, err := json.Marshal(genReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	reqURL := apiURLModels + url.PathEscape(model) + method
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, reqURL, bytes.NewBuffer(reqBody))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create HTTP request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Goog-Api-Key", c.token)
+
+	resp, err := c.hc.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("HTTP request failed: %w", err)
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		defer resp.Body.Close()
+		respBody, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("unexpected status code: %d, response: %s", resp.StatusCode, string(respBody))
+	}
+
+	return resp, nil
+}
+
+func responseText(resp *generateContentResponse) (string, error) {
+	if resp.PromptFeedback != nil && resp.PromptFeedback.BlockReason != "" {
+		return "", fmt.Errorf("prompt blocked: %s", resp.PromptFeedback.BlockReason)
+	}
+
+	if len(resp.Candidates) == 0 {
+		return "", nil
+	}
+
+	return candidateText(resp.Candidates[0]), nil
+}
+
+func candidateText(c candidate) string {
+	var text strings.Builder
+	for _, p := range c.Content.Parts {
+		text.WriteString(p.Text)
+	}
+	return text.String()
+}
+
+func newGenerateContentRequest(chat *domain.Chat) (*generateContentRequest, error) {
+	contents := make([]content, 0, len(chat.Messages))
+
+	for _, msg := range chat.Messages {
+		if msg.Role == domain.MessageRoleTool || len(msg.ToolCalls) > 0 {
+			continue // tool calls of other providers are not replayed
+		}
+
+		parts := make([]part, 0, len(msg.ContentParts))
+		for _, cp := range msg.ContentParts {
+			switch cp.Type {
+			case domain.ContentPartTypeText:
+				parts = append(parts, part{Text: cp.Data})
+			case domain.ContentPartTypeImage:
+				parts = append(parts, newImagePart(cp))
+			default:
+				return nil, errors.New("unsupported content type")
+			}
+		}
+
+		role := contentRoleUser
+		if msg.Role == domain.MessageRoleAssistant {
+			role = contentRoleModel
+		}
+
+		contents = append(contents, content{Role: role, Parts: parts})
+	}
+
+	genReq := &generateContentRequest{
+		Contents: contents,
+		GenerationConfig: generationConfig{
+			MaxOutputTokens:  lo.CoalesceOrEmpty(chat.Params.MaxTokens, defaultMaxTokens),
+			Temperature:      chat.Params.Temperature,
+			TopP:             chat.Params.TopP,
+			PresencePenalty:  chat.Params.PresencePenalty,
+			FrequencyPenalty: chat.Params.FrequencyPenalty,
+			Seed:             chat.Params.Seed,
+		},
+	}
+
+	if chat.Choices > 1 {
+		genReq.GenerationConfig.CandidateCount = chat.Choices
+	}
+
+	if chat.SystemPrompt != "" {
+		genReq.SystemInstruction = &content{Parts: []part{{Text: chat.SystemPrompt}}}
+	}
+
+	return genReq, nil
+}
+
+func newImagePart(cp domain.ContentPart) part {
+	if mimeType, data, ok := cp.InlineImage(); ok {
+		return part{InlineData: &inlineData{MimeType: mimeType, Data: data}}
+	}
+	return part{FileData: &fileData{FileURI: cp.Data}}
+}
@@ -0,0 +1,55 @@
+package httpclient
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// Config describes the timeouts and the proxy of outgoing connections. Zero timeouts mean no limit.
+type Config struct {
+	// ConnectTimeout limits dialing, including the TLS handshake.
+	ConnectTimeout time.Duration
+	// ResponseHeaderTimeout limits waiting for the response headers once the request is sent.
+	ResponseHeaderTimeout time.Duration
+	// Timeout limits a whole request, including reading the response body.
+	Timeout time.Duration
+	// ProxyURL is an http, https, socks5 or socks5h proxy. Without it HTTP_PROXY, HTTPS_PROXY
+	// and NO_PROXY from the environment are used.
+	ProxyURL string
+}
+
+// New returns a client with its own transport configured by cfg.
+func New(cfg Config) (*http.Client, error) {
+	proxy := http.ProxyFromEnvironment
+	if cfg.ProxyURL != "" {
+		proxyURL, err := url.Parse(cfg.ProxyURL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy url: %w", err)
+		}
+
+		switch proxyURL.Scheme {
+		case "http", "https", "socks5", "socks5h":
+		default:
+			return nil, fmt.Errorf("unsupported proxy scheme %q, expected http, https, socks5 or socks5h", proxyURL.Scheme)
+		}
+
+		proxy = http.ProxyURL(proxyURL)
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = proxy
+	transport.DialContext = (&net.Dialer{
+		Timeout:   cfg.ConnectTimeout,
+		KeepAlive: 30 * time.Second,
+	}).DialContext
+	transport.TLSHandshakeTimeout = cfg.ConnectTimeout
+	transport.ResponseHeaderTimeout = cfg.ResponseHeaderTimeout
+
+	return &http.Client{
+		Transport: transport,
+		Timeout:   cfg.Timeout,
+	}, nil
+}
@@ -0,0 +1,170 @@
+package knowledge
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/dskvich/chatgpt-telegram-bot/pkg/domain"
+	"github.com/dskvich/chatgpt-telegram-bot/pkg/llm"
+)
+
+const (
+	chunkTokens = 500
+	// maxChunksPerDocument bounds the embedding cost of a single document.
+	maxChunksPerDocument = 2000
+
+	searchLimit = 4
+	// Chunks less similar to the question do not help to answer it.
+	minSearchSimilarity = 0.25
+
+	// maxCachedChats bounds the chunks kept in memory between questions.
+	maxCachedChats = 100
+)
+
+type embedder interface {
+	CreateEmbeddings(ctx context.Context, inputs []string) ([][]float32, domain.Usage, error)
+}
+
+type store interface {
+	SaveDocument(ctx context.Context, doc domain.KnowledgeDocument, chunks []domain.KnowledgeChunk) (int64, error)
+	ListDocuments(ctx context.Context, chatID int64) ([]domain.KnowledgeDocument, error)
+	DeleteDocument(ctx context.Context, chatID, id int64) error
+	ListChunks(ctx context.Context, chatID int64) ([]domain.KnowledgeChunk, error)
+}
+
+// base is the knowledge base of every chat: documents are chunked and embedded when added,
+// and the chunks closest to a question are retrieved to answer it. The chunks of a chat are
+// loaded once and kept until its documents change, an empty knowledge base included, so that
+// messages in chats without documents do not query the database.
+type base struct {
+	embedder embedder
+	store    store
+
+	mu     sync.Mutex
+	chunks map[int64][]domain.KnowledgeChunk
+	// changes counts forgotten chats, so that chunks loaded before a change are not cached.
+	changes uint64
+}
+
+func NewBase(embedder embedder, store store) *base {
+	return &base{
+		embedder: embedder,
+		store:    store,
+		chunks:   make(map[int64][]domain.KnowledgeChunk),
+	}
+}
+
+// Add chunks and embeds the text and stores it in the knowledge base of the chat.
+// The returned usage is spent even when storing fails.
+func (b *base) Add(ctx context.Context, chatID int64, title, text string) (*domain.KnowledgeDocument, domain.Usage, error) {
+	parts := llm.SplitText(text, chunkTokens)
+	if len(parts) == 0 {
+		return nil, domain.Usage{}, errors.New("document has no text")
+	}
+	if len(parts) > maxChunksPerDocument {
+		return nil, domain.Usage{}, fmt.Errorf("document is too large: %d chunks, at most %d", len(parts), maxChunksPerDocument)
+	}
+
+	embeddings, usage, err := b.embedder.CreateEmbeddings(ctx, parts)
+	if err != nil {
+		return nil, usage, fmt.Errorf("embedding chunks: %w", err)
+	}
+
+	chunks := make([]domain.KnowledgeChunk, len(parts))
+	for i, part := range parts {
+		chunks[i] = domain.KnowledgeChunk{Position: i, Content: part, Embedding: embeddings[i]}
+	}
+
+	doc := domain.KnowledgeDocument{ChatID: chatID, Title: title, Chunks: len(chunks)}
+	doc.ID, err = b.store.SaveDocument(ctx, doc, chunks)
+	b.forget(chatID)
+	if err != nil {
+		return nil, usage, err
+	}
+
+	return &doc, usage, nil
+}
+
+func (b *base) List(ctx context.Context, chatID int64) ([]domain.KnowledgeDocument, error) {
+	return b.store.ListDocuments(ctx, chatID)
+}
+
+func (b *base) Remove(ctx context.Context, chatID, id int64) error {
+	defer b.forget(chatID)
+	return b.store.DeleteDocument(ctx, chatID, id)
+}
+
+// Search returns the chunks of the knowledge base of the chat most relevant to the question,
+// the most similar first. Chats without a knowledge base cost nothing.
+func (b *base) Search(ctx context.Context, chatID int64, question string) ([]domain.KnowledgeChunk, domain.Usage, error) {
+	chunks, err := b.cachedChunks(ctx, chatID)
+	if err != nil {
+		return nil, domain.Usage{}, err
+	}
+	if len(chunks) == 0 || strings.TrimSpace(question) == "" {
+		return nil, domain.Usage{}, nil
+	}
+
+	embeddings, usage, err := b.embedder.CreateEmbeddings(ctx, []string{question})
+	if err != nil {
+		return nil, usage, fmt.Errorf("embedding question: %w", err)
+	}
+
+	candidates := make([][]float32, len(chunks))
+	for i, c := range chunks {
+		candidates[i] = c.Embedding
+	}
+
+	order := llm.RankBySimilarity(embeddings[0], candidates, minSearchSimilarity, searchLimit)
+
+	relevant := make([]domain.KnowledgeChunk, len(order))
+	for i, idx := range order {
+		relevant[i] = chunks[idx]
+	}
+
+	return relevant, usage, nil
+}
+
+// cachedChunks returns the chunks of the chat, loading them from the store on the first question.
+func (b *base) cachedChunks(ctx context.Context, chatID int64) ([]domain.KnowledgeChunk, error) {
+	b.mu.Lock()
+	chunks, ok := b.chunks[chatID]
+	changes := b.changes
+	b.mu.Unlock()
+	if ok {
+		return chunks, nil
+	}
+
+	chunks, err := b.store.ListChunks(ctx, chatID)
+	if err != nil {
+		return nil, err
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.changes != changes {
+		return chunks, nil
+	}
+	if len(b.chunks) >= maxCachedChats {
+		for id := range b.chunks {
+			delete(b.chunks, id)
+			break
+		}
+	}
+	b.chunks[chatID] = chunks
+
+	return chunks, nil
+}
+
+// forget drops the cached chunks of the chat after its documents have changed.
+func (b *base) forget(chatID int64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	delete(b.chunks, chatID)
+	b.changes++
+}
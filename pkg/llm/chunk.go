@@ -0,0 +1,43 @@
+package llm
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// SplitText cuts a text into chunks of at most maxTokens estimated tokens. Chunks end at a
+// paragraph break when possible, then at a line break, then at a space.
+func SplitText(text string, maxTokens int) []string {
+	limit := max(maxTokens, 1) * bytesPerToken
+
+	var chunks []string
+	appendChunk := func(chunk string) {
+		if chunk = strings.TrimSpace(chunk); chunk != "" {
+			chunks = append(chunks, chunk)
+		}
+	}
+
+	for len(text) > limit {
+		head := text[:limit]
+
+		cut := strings.LastIndex(head, "\n\n")
+		if cut <= 0 {
+			cut = strings.LastIndex(head, "\n")
+		}
+		if cut <= 0 {
+			cut = strings.LastIndex(head, " ")
+		}
+		if cut <= 0 {
+			cut = limit
+			for cut > 0 && !utf8.RuneStart(text[cut]) {
+				cut--
+			}
+		}
+
+		appendChunk(text[:cut])
+		text = text[cut:]
+	}
+	appendChunk(text)
+
+	return chunks
+}
@@ -0,0 +1,233 @@
+package llm
+
+import (
+	"bytes"
+	"encoding/base64"
+	"image"
+	_ "image/gif"  // register decoders for image.DecodeConfig
+	_ "image/jpeg" // register decoders for image.DecodeConfig
+	_ "image/png"  // register decoders for image.DecodeConfig
+	"math"
+	"strings"
+
+	"github.com/dskvich/chatgpt-telegram-bot/pkg/domain"
+)
+
+const (
+	defaultContextWindow = 8192
+	// ReservedOutputTokens is kept free in the context window for the answer.
+	ReservedOutputTokens = 4096
+	// ReasoningOutputTokens is the default output limit of reasoning models, which also covers their
+	// hidden reasoning.
+	ReasoningOutputTokens = 25_000
+
+	bytesPerToken         = 4
+	messageOverheadTokens = 4
+
+	// Image costs follow the OpenAI high detail formula: the image is fit into 2048x2048, scaled so
+	// that the short side is 768 and billed per 512px tile.
+	imageBaseTokens     = 85
+	imageTileTokens     = 170
+	imageTileSize       = 512
+	imageMaxSide        = 2048
+	imageShortSide      = 768
+	defaultImageTokens  = imageBaseTokens + 4*imageTileTokens
+	imageHeaderMaxBytes = 64 * 1024
+)
+
+// contextWindows maps model name prefixes to their context window in tokens. The longest matching
+// prefix wins, so dated snapshots such as gpt-4o-2024-08-06 resolve to their family.
+var contextWindows = map[string]int{
+	"gpt-3.5-turbo": 16_385,
+	"gpt-4":         8_192,
+	"gpt-4-turbo":   128_000,
+	"gpt-4o":        128_000,
+	"gpt-4.1":       1_047_576,
+	"o1":            200_000,
+	"o1-mini":       128_000,
+	"o1-preview":    128_000,
+	"o3":            200_000,
+	"o4":            200_000,
+	"gpt-5":         400_000,
+	"gpt-5-chat":    128_000,
+	"claude":        200_000,
+	"gemini-1.5":    1_048_576,
+	"gemini-2":      1_048_576,
+}
+
+// outputLimits maps model name prefixes to the most tokens the model can answer with,
+// matched the same way as contextWindows.
+var outputLimits = map[string]int{
+	"gpt-3.5-turbo": 4_096,
+	"gpt-4":         8_192,
+	"gpt-4-turbo":   4_096,
+	"gpt-4o":        16_384,
+	"gpt-4.1":       32_768,
+	"o1":            100_000,
+	"o1-mini":       65_536,
+	"o1-preview":    32_768,
+	"o3":            100_000,
+	"o4":            100_000,
+	"gpt-5":         128_000,
+	"gpt-5-chat":    16_384,
+	"claude":        8_192,
+	"gemini":        8_192,
+}
+
+// ContextWindow returns the context window of the model in tokens.
+func ContextWindow(model string) int {
+	return byPrefix(contextWindows, model, defaultContextWindow)
+}
+
+// MaxOutputTokens returns the most tokens the model can answer with.
+func MaxOutputTokens(model string) int {
+	return byPrefix(outputLimits, model, ReservedOutputTokens)
+}
+
+// byPrefix looks the model up by the longest matching prefix.
+func byPrefix(sizes map[string]int, model string, fallback int) int {
+	if size, ok := LongestPrefix(sizes, model); ok {
+		return size
+	}
+	return fallback
+}
+
+// LongestPrefix returns the value of the longest key the model name starts with, so that
+// "gpt-4o-2024-08-06" gets the value of "gpt-4o" and "gpt-4o-mini" keeps its own.
+func LongestPrefix[V any](values map[string]V, model string) (V, bool) {
+	var (
+		value   V
+		matched = -1
+	)
+	for prefix, v := range values {
+		if strings.HasPrefix(model, prefix) && len(prefix) > matched {
+			value, matched = v, len(prefix)
+		}
+	}
+	return value, matched >= 0
+}
+
+// reasoningPrefixes are the names of the models that think before answering.
+var reasoningPrefixes = []string{"o1", "o3", "o4", "gpt-5"}
+
+// IsReasoningModel reports whether the model spends hidden tokens on reasoning before it answers.
+// The gpt-5-chat models are the non-reasoning variants of gpt-5.
+func IsReasoningModel(model string) bool {
+	if strings.HasPrefix(model, "gpt-5-chat") {
+		return false
+	}
+	for _, prefix := range reasoningPrefixes {
+		if strings.HasPrefix(model, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// OutputTokens returns how many tokens the answer of the model may take by default.
+func OutputTokens(model string) int {
+	if IsReasoningModel(model) {
+		return ReasoningOutputTokens
+	}
+	return ReservedOutputTokens
+}
+
+// EstimateTokens approximates the prompt tokens of a message. Text is counted as four bytes per
+// token, which overestimates English and is close for Cyrillic, so the estimate errs on the safe side.
+func EstimateTokens(msg domain.Message) int {
+	tokens := messageOverheadTokens
+	for _, part := range msg.ContentParts {
+		switch part.Type {
+		case domain.ContentPartTypeText:
+			tokens += EstimateTextTokens(part.Data)
+		case domain.ContentPartTypeImage:
+			tokens += estimateImageTokens(part)
+		}
+	}
+	for _, call := range msg.ToolCalls {
+		tokens += EstimateTextTokens(call.Name) + EstimateTextTokens(call.Arguments)
+	}
+	return tokens
+}
+
+// EstimateTextTokens approximates the tokens of a text the same way as EstimateTokens.
+func EstimateTextTokens(text string) int {
+	return (len(text) + bytesPerToken - 1) / bytesPerToken
+}
+
+func estimateImageTokens(part domain.ContentPart) int {
+	_, data, ok := part.InlineImage()
+	if !ok {
+		return defaultImageTokens
+	}
+
+	// The header is enough to read the dimensions.
+	header := data[:min(len(data), base64.StdEncoding.EncodedLen(imageHeaderMaxBytes))]
+	header = header[:len(header)/4*4]
+	raw, err := base64.StdEncoding.DecodeString(header)
+	if err != nil {
+		return defaultImageTokens
+	}
+
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(raw))
+	if err != nil || cfg.Width == 0 || cfg.Height == 0 {
+		return defaultImageTokens
+	}
+
+	w, h := float64(cfg.Width), float64(cfg.Height)
+	if scale := imageMaxSide / math.Max(w, h); scale < 1 {
+		w, h = w*scale, h*scale
+	}
+	if scale := imageShortSide / math.Min(w, h); scale < 1 {
+		w, h = w*scale, h*scale
+	}
+
+	tiles := math.Ceil(w/imageTileSize) * math.Ceil(h/imageTileSize)
+	return imageBaseTokens + imageTileTokens*int(tiles)
+}
+
+// FitContext drops the oldest turns of the chat until the system prompt, the history and
+// outputTokens of the answer fit into the model context window and at most maxTurns turns are left
+// (zero means no limit). A turn is a user message with everything that answers it, so tool calls
+// are never separated from their results. The latest turn is always kept.
+func FitContext(chat *domain.Chat, maxTurns, outputTokens int) {
+	turns := splitTurns(chat.Messages)
+	if len(turns) == 0 {
+		return
+	}
+
+	budget := ContextWindow(chat.Model) - outputTokens - EstimateTextTokens(chat.SystemPrompt)
+
+	used, kept := 0, 0
+	for i := len(turns) - 1; i >= 0; i-- {
+		tokens := 0
+		for _, msg := range turns[i] {
+			tokens += EstimateTokens(msg)
+		}
+
+		if kept > 0 && (used+tokens > budget || maxTurns > 0 && kept >= maxTurns) {
+			break
+		}
+		used += tokens
+		kept++
+	}
+
+	if dropped := len(turns) - kept; dropped > 0 {
+		var messages []domain.Message
+		for _, turn := range turns[dropped:] {
+			messages = append(messages, turn...)
+		}
+		chat.Messages = messages
+	}
+}
+
+func splitTurns(messages []domain.Message) [][]domain.Message {
+	var turns [][]domain.Message
+	for _, msg := range messages {
+		if msg.Role == domain.MessageRoleUser || len(turns) == 0 {
+			turns = append(turns, nil)
+		}
+		turns[len(turns)-1] = append(turns[len(turns)-1], msg)
+	}
+	return turns
+}
@@ -0,0 +1,27 @@
+package llm
+
+import (
+	"path"
+	"slices"
+)
+
+// FilterModels keeps the models matching at least one allow pattern and no deny pattern.
+// Patterns use path.Match syntax, e.g. "gpt-4o*". The result is sorted.
+func FilterModels(models, allow, deny []string) []string {
+	matchAny := func(model string, patterns []string) bool {
+		return slices.ContainsFunc(patterns, func(pattern string) bool {
+			ok, _ := path.Match(pattern, model)
+			return ok
+		})
+	}
+
+	var res []string
+	for _, model := range models {
+		if matchAny(model, allow) && !matchAny(model, deny) {
+			res = append(res, model)
+		}
+	}
+	slices.Sort(res)
+
+	return slices.Compact(res)
+}
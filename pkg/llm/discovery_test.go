@@ -0,0 +1,51 @@
+package llm
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestFilterModels(t *testing.T) {
+	allow := []string{"gpt-*", "o1*", "o3*"}
+	deny := []string{"*audio*", "*-pro*", "*deep-research*"}
+
+	tests := []struct {
+		name   string
+		models []string
+		want   []string
+	}{
+		{
+			name:   "keeps allowed models sorted",
+			models: []string{"o3-mini", "gpt-4o", "gpt-4o-mini", "o1"},
+			want:   []string{"gpt-4o", "gpt-4o-mini", "o1", "o3-mini"},
+		},
+		{
+			name:   "drops models not allowed",
+			models: []string{"gpt-4o", "dall-e-3", "whisper-1", "text-embedding-3-small"},
+			want:   []string{"gpt-4o"},
+		},
+		{
+			name:   "deny wins over allow",
+			models: []string{"gpt-4o-audio-preview", "o1-pro", "o3-deep-research", "o3"},
+			want:   []string{"o3"},
+		},
+		{
+			name:   "drops duplicates",
+			models: []string{"gpt-4o", "gpt-4o"},
+			want:   []string{"gpt-4o"},
+		},
+		{
+			name:   "nothing left",
+			models: []string{"dall-e-3"},
+			want:   nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := FilterModels(tt.models, allow, deny); !slices.Equal(got, tt.want) {
+				t.Errorf("FilterModels() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,124 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+
+	"github.com/dskvich/chatgpt-telegram-bot/pkg/domain"
+)
+
+// Provider is a vendor API able to answer a chat.
+type Provider interface {
+	CreateChatCompletion(ctx context.Context, chat *domain.Chat) (*domain.Completion, error)
+	StreamChatCompletion(ctx context.Context, chat *domain.Chat, onDelta func(delta string)) (*domain.Completion, error)
+}
+
+// registry routes chat completions to the provider that serves the chat model.
+type registry struct {
+	mu        sync.RWMutex
+	models    []string
+	providers map[string]Provider
+}
+
+func NewRegistry() *registry {
+	return &registry{
+		providers: make(map[string]Provider),
+	}
+}
+
+// Register makes the provider serve the given models. A model registered twice is served by
+// the provider registered last.
+func (r *registry) Register(provider Provider, models ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, model := range models {
+		if _, ok := r.providers[model]; !ok {
+			r.models = append(r.models, model)
+		}
+		r.providers[model] = provider
+	}
+}
+
+// SetModels replaces the models served by the provider. The new models take the place of the
+// old ones in the list, so refreshing one provider does not reorder the others.
+func (r *registry) SetModels(provider Provider, models ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var (
+		list     []string
+		inserted bool
+	)
+	insert := func() {
+		for _, model := range models {
+			owner, ok := r.providers[model]
+			if (!ok || owner == provider) && !slices.Contains(list, model) {
+				list = append(list, model)
+			}
+		}
+		inserted = true
+	}
+
+	for _, model := range r.models {
+		if r.providers[model] != provider {
+			list = append(list, model)
+			continue
+		}
+		if !inserted {
+			insert()
+		}
+	}
+	if !inserted {
+		insert()
+	}
+
+	for model, p := range r.providers {
+		if p == provider {
+			delete(r.providers, model)
+		}
+	}
+	for _, model := range list {
+		if _, ok := r.providers[model]; !ok {
+			r.providers[model] = provider
+		}
+	}
+	r.models = list
+}
+
+// Models returns the registered model names in registration order.
+func (r *registry) Models() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return slices.Clone(r.models)
+}
+
+func (r *registry) CreateChatCompletion(ctx context.Context, chat *domain.Chat) (*domain.Completion, error) {
+	provider, err := r.provider(chat.Model)
+	if err != nil {
+		return nil, err
+	}
+	return provider.CreateChatCompletion(ctx, chat)
+}
+
+func (r *registry) StreamChatCompletion(ctx context.Context, chat *domain.Chat, onDelta func(delta string)) (*domain.Completion, error) {
+	provider, err := r.provider(chat.Model)
+	if err != nil {
+		return nil, err
+	}
+	return provider.StreamChatCompletion(ctx, chat, onDelta)
+}
+
+func (r *registry) provider(model string) (Provider, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	provider, ok := r.providers[model]
+	if !ok {
+		return nil, fmt.Errorf("%w: no provider registered for model %q", domain.ErrInvalidModel, model)
+	}
+	return provider, nil
+}
@@ -0,0 +1,93 @@
+package llm
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/dskvich/chatgpt-telegram-bot/pkg/domain"
+)
+
+type fakeProvider struct{ name string }
+
+func (f *fakeProvider) CreateChatCompletion(context.Context, *domain.Chat) (*domain.Completion, error) {
+	return nil, nil
+}
+
+func (f *fakeProvider) StreamChatCompletion(context.Context, *domain.Chat, func(string)) (*domain.Completion, error) {
+	return nil, nil
+}
+
+func TestRegistrySetModels(t *testing.T) {
+	openai, anthropic, gemini := &fakeProvider{"openai"}, &fakeProvider{"anthropic"}, &fakeProvider{"gemini"}
+
+	tests := []struct {
+		name     string
+		provider *fakeProvider
+		models   []string
+		want     []string
+		owners   map[string]*fakeProvider
+	}{
+		{
+			name:     "replaces the models in their place",
+			provider: anthropic,
+			models:   []string{"claude-sonnet", "claude-haiku"},
+			want:     []string{"gpt-4o", "gpt-4o-mini", "claude-sonnet", "claude-haiku", "gemini-flash"},
+			owners:   map[string]*fakeProvider{"claude-sonnet": anthropic, "gemini-flash": gemini},
+		},
+		{
+			name:     "keeps the models of other providers in order",
+			provider: openai,
+			models:   []string{"o3", "gpt-4o"},
+			want:     []string{"o3", "gpt-4o", "claude-haiku", "gemini-flash"},
+			owners:   map[string]*fakeProvider{"o3": openai, "claude-haiku": anthropic},
+		},
+		{
+			name:     "does not take models of another provider",
+			provider: openai,
+			models:   []string{"gpt-4o", "gemini-flash"},
+			want:     []string{"gpt-4o", "claude-haiku", "gemini-flash"},
+			owners:   map[string]*fakeProvider{"gpt-4o": openai, "gemini-flash": gemini},
+		},
+		{
+			name:     "drops the models of the provider",
+			provider: openai,
+			models:   nil,
+			want:     []string{"claude-haiku", "gemini-flash"},
+			owners:   map[string]*fakeProvider{"gpt-4o-mini": nil},
+		},
+		{
+			name:     "appends a provider without models",
+			provider: &fakeProvider{"local"},
+			models:   []string{"llama"},
+			want:     []string{"gpt-4o", "gpt-4o-mini", "claude-haiku", "gemini-flash", "llama"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := NewRegistry()
+			r.Register(openai, "gpt-4o", "gpt-4o-mini")
+			r.Register(anthropic, "claude-haiku")
+			r.Register(gemini, "gemini-flash")
+
+			r.SetModels(tt.provider, tt.models...)
+
+			if got := r.Models(); !slices.Equal(got, tt.want) {
+				t.Errorf("Models() = %v, want %v", got, tt.want)
+			}
+			for model, want := range tt.owners {
+				got, err := r.provider(model)
+				if want == nil {
+					if err == nil {
+						t.Errorf("provider(%q) = %v, want an error", model, got)
+					}
+					continue
+				}
+				if got != want {
+					t.Errorf("provider(%q) = %v, want %v", model, got, want)
+				}
+			}
+		})
+	}
+}
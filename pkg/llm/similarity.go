@@ -0,0 +1,51 @@
+package llm
+
+import (
+	"cmp"
+	"math"
+	"slices"
+)
+
+// CosineSimilarity returns the cosine of the angle between two embeddings, zero when they
+// differ in length or one of them is empty.
+func CosineSimilarity(a, b []float32) float64 {
+	if len(a) != len(b) || len(a) == 0 {
+		return 0
+	}
+
+	var dot, normA, normB float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		normA += float64(a[i]) * float64(a[i])
+		normB += float64(b[i]) * float64(b[i])
+	}
+
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB))
+}
+
+// RankBySimilarity returns the indexes of the candidates at least minSimilarity similar to the query,
+// the most similar first and equally similar ones in their original order. A limit above zero keeps
+// only that many; a minSimilarity of math.Inf(-1) keeps every candidate.
+func RankBySimilarity(query []float32, candidates [][]float32, minSimilarity float64, limit int) []int {
+	scores := make([]float64, len(candidates))
+	order := make([]int, 0, len(candidates))
+	for i, candidate := range candidates {
+		scores[i] = CosineSimilarity(query, candidate)
+		if scores[i] >= minSimilarity {
+			order = append(order, i)
+		}
+	}
+
+	slices.SortStableFunc(order, func(a, b int) int {
+		return cmp.Compare(scores[b], scores[a])
+	})
+
+	if limit > 0 && len(order) > limit {
+		order = order[:limit]
+	}
+	return order
+}
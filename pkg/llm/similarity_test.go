@@ -0,0 +1,40 @@
+package llm
+
+import (
+	"math"
+	"slices"
+	"testing"
+)
+
+func TestRankBySimilarity(t *testing.T) {
+	query := []float32{1, 0}
+	candidates := [][]float32{
+		{0, 1},    // 0: orthogonal
+		{1, 0},    // 1: same direction
+		{1, 1},    // 2: 45 degrees
+		{-1, 0},   // 3: opposite
+		{2, 0},    // 4: same direction, longer
+		{1, 0, 0}, // 5: another length, similarity 0
+	}
+
+	tests := []struct {
+		name          string
+		minSimilarity float64
+		limit         int
+		want          []int
+	}{
+		{"keeps every candidate", math.Inf(-1), 0, []int{1, 4, 2, 0, 5, 3}},
+		{"drops less similar", 0.5, 0, []int{1, 4, 2}},
+		{"limits the result", 0.5, 2, []int{1, 4}},
+		{"nothing similar enough", 1.5, 0, []int{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := RankBySimilarity(query, candidates, tt.minSimilarity, tt.limit)
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("RankBySimilarity() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
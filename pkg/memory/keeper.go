@@ -0,0 +1,218 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+
+	"github.com/dskvich/chatgpt-telegram-bot/pkg/domain"
+	"github.com/dskvich/chatgpt-telegram-bot/pkg/llm"
+	"github.com/dskvich/chatgpt-telegram-bot/pkg/logger"
+)
+
+const (
+	// maxMemoriesPerUser bounds the memories of a user, the oldest ones are forgotten first.
+	maxMemoriesPerUser = 200
+	maxFactsPerChat    = 10
+	// maxTranscriptLength keeps the end of long chats, where the conversation settled.
+	maxTranscriptLength = 12_000
+
+	recallLimit = 5
+	// Memories less similar to the question are not relevant enough to spend tokens on.
+	minRecallSimilarity = 0.3
+	// A new fact this similar to a known one is a duplicate.
+	duplicateSimilarity = 0.9
+
+	noFactsMarker = "NONE"
+
+	extractionPrompt = `You maintain a long-term memory about the user of a chat assistant.
+Read the conversation and list durable facts about the user worth remembering for future conversations:
+preferences, background, ongoing projects, people and things they care about.
+Skip small talk, one-off questions and anything about the assistant itself.
+Write one short self-contained fact per line starting with "- ", in the language of the conversation.
+Answer with ` + noFactsMarker + ` if there is nothing worth remembering.`
+)
+
+type embedder interface {
+	CreateEmbeddings(ctx context.Context, inputs []string) ([][]float32, domain.Usage, error)
+}
+
+type chatCompleter interface {
+	CreateChatCompletion(ctx context.Context, chat *domain.Chat) (*domain.Completion, error)
+}
+
+type store interface {
+	Save(ctx context.Context, memory domain.Memory) error
+	ListByUser(ctx context.Context, userID int64) ([]domain.Memory, error)
+	Prune(ctx context.Context, userID int64, keep int) error
+}
+
+type usageSaver interface {
+	Save(ctx context.Context, record domain.UsageRecord) error
+}
+
+// keeper extracts facts about users from finished chats and recalls the relevant ones
+// when a new chat starts.
+type keeper struct {
+	embedder  embedder
+	completer chatCompleter
+	store     store
+	usage     usageSaver
+	model     string
+}
+
+func NewKeeper(embedder embedder, completer chatCompleter, store store, usage usageSaver) *keeper {
+	return &keeper{
+		embedder:  embedder,
+		completer: completer,
+		store:     store,
+		usage:     usage,
+		model:     domain.Gpt4oMiniModel,
+	}
+}
+
+// Remember extracts facts about the user from the chat and stores the ones not known yet.
+func (k *keeper) Remember(ctx context.Context, userID int64, chat domain.Chat) error {
+	transcript := transcriptOf(chat)
+	if transcript == "" {
+		return nil
+	}
+
+	extraction := &domain.Chat{
+		ID:           chat.ID,
+		TopicID:      chat.TopicID,
+		Model:        k.model,
+		SystemPrompt: extractionPrompt,
+		Messages: []domain.Message{{
+			Role:         domain.MessageRoleUser,
+			ContentParts: []domain.ContentPart{{Type: domain.ContentPartTypeText, Data: transcript}},
+		}},
+	}
+
+	completion, err := k.completer.CreateChatCompletion(ctx, extraction)
+	if err != nil {
+		return fmt.Errorf("extracting facts: %w", err)
+	}
+	k.saveUsage(ctx, userID, chat, k.model, completion.Usage)
+
+	facts := parseFacts(completion.Message.ContentParts[0].Data)
+	if len(facts) == 0 {
+		return nil
+	}
+
+	embeddings, usage, err := k.embedder.CreateEmbeddings(ctx, facts)
+	if err != nil {
+		return fmt.Errorf("embedding facts: %w", err)
+	}
+	k.saveUsage(ctx, userID, chat, domain.TextEmbedding3SmallModel, usage)
+
+	known, err := k.store.ListByUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	saved := 0
+	for i, fact := range facts {
+		isDuplicate := slices.ContainsFunc(known, func(m domain.Memory) bool {
+			return llm.CosineSimilarity(m.Embedding, embeddings[i]) >= duplicateSimilarity
+		})
+		if isDuplicate {
+			continue
+		}
+
+		memory := domain.Memory{UserID: userID, Content: fact, Embedding: embeddings[i]}
+		if err := k.store.Save(ctx, memory); err != nil {
+			return err
+		}
+		known = append(known, memory)
+		saved++
+	}
+
+	slog.InfoContext(ctx, "Remembered facts from the chat", "userID", userID, "extracted", len(facts), "saved", saved)
+
+	return k.store.Prune(ctx, userID, maxMemoriesPerUser)
+}
+
+// Recall returns the memories of the user most relevant to the question, the most similar first.
+func (k *keeper) Recall(ctx context.Context, userID int64, chat domain.Chat, question string) ([]domain.Memory, error) {
+	memories, err := k.store.ListByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if len(memories) == 0 || strings.TrimSpace(question) == "" {
+		return nil, nil
+	}
+
+	embeddings, usage, err := k.embedder.CreateEmbeddings(ctx, []string{question})
+	if err != nil {
+		return nil, fmt.Errorf("embedding question: %w", err)
+	}
+	k.saveUsage(ctx, userID, chat, domain.TextEmbedding3SmallModel, usage)
+
+	candidates := make([][]float32, len(memories))
+	for i, m := range memories {
+		candidates[i] = m.Embedding
+	}
+
+	order := llm.RankBySimilarity(embeddings[0], candidates, minRecallSimilarity, recallLimit)
+
+	res := make([]domain.Memory, len(order))
+	for i, idx := range order {
+		res[i] = memories[idx]
+	}
+
+	return res, nil
+}
+
+func (k *keeper) saveUsage(ctx context.Context, userID int64, chat domain.Chat, model string, usage domain.Usage) {
+	record := domain.UsageRecord{
+		UserID:  userID,
+		ChatID:  chat.ID,
+		TopicID: chat.TopicID,
+		Model:   model,
+		Usage:   usage,
+	}
+	if err := k.usage.Save(ctx, record); err != nil {
+		slog.ErrorContext(ctx, "Failed to save usage", logger.Err(err))
+	}
+}
+
+// transcriptOf renders the text of the chat, tool calls and images are left out.
+func transcriptOf(chat domain.Chat) string {
+	var sb strings.Builder
+	for _, msg := range chat.Messages {
+		if msg.Role != domain.MessageRoleUser && msg.Role != domain.MessageRoleAssistant || len(msg.ToolCalls) > 0 {
+			continue
+		}
+		for _, part := range msg.ContentParts {
+			if part.Type == domain.ContentPartTypeText && strings.TrimSpace(part.Data) != "" {
+				fmt.Fprintf(&sb, "%s: %s\n\n", msg.Role, part.Data)
+			}
+		}
+	}
+
+	transcript := sb.String()
+	if len(transcript) > maxTranscriptLength {
+		transcript = strings.ToValidUTF8(transcript[len(transcript)-maxTranscriptLength:], "")
+	}
+
+	return strings.TrimSpace(transcript)
+}
+
+func parseFacts(text string) []string {
+	var facts []string
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		fact, ok := strings.CutPrefix(line, "- ")
+		if !ok || fact == "" || fact == noFactsMarker {
+			continue
+		}
+		facts = append(facts, strings.TrimSpace(fact))
+		if len(facts) == maxFactsPerChat {
+			break
+		}
+	}
+	return facts
+}
@@ -0,0 +1,373 @@
+package openai
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/dskvich/chatgpt-telegram-bot/pkg/domain"
+	"github.com/dskvich/chatgpt-telegram-bot/pkg/sse"
+	"github.com/samber/lo"
+)
+
+// maxToolRounds bounds the tool-call loop. The last round is sent without tools,
+// so the model has to answer with what it has; asking for tools again then is an error.
+const maxToolRounds = 5
+
+// ToolExecutor provides the tools offered to the model and runs the ones it calls.
+type ToolExecutor interface {
+	Definitions() []domain.ToolDefinition
+	Call(ctx context.Context, name, arguments string) (string, error)
+}
+
+// chatTurn is a single model response within the tool-call loop.
+type chatTurn struct {
+	role      string
+	content   string
+	toolCalls []chatToolCall
+	usage     *chatCompletionUsage
+	// alternatives holds the content of every choice when several were requested.
+	alternatives []string
+}
+
+func (c *client) CreateChatCompletion(ctx context.Context, chat *domain.Chat) (*domain.Completion, error) {
+	return c.complete(ctx, chat, c.createTurn)
+}
+
+// StreamChatCompletion requests a completion with `stream: true` and calls onDelta for every
+// content chunk as it arrives. The fully assembled message is returned once the stream ends.
+func (c *client) StreamChatCompletion(ctx context.Context, chat *domain.Chat, onDelta func(delta string)) (*domain.Completion, error) {
+	return c.complete(ctx, chat, func(ctx context.Context, chatReq *chatCompletionRequest) (*chatTurn, error) {
+		return c.streamTurn(ctx, chatReq, onDelta)
+	})
+}
+
+// complete runs the tool-call loop: while the model asks for tools, they are executed and their
+// results are sent back, until the model returns a final answer.
+func (c *client) complete(
+	ctx context.Context,
+	chat *domain.Chat,
+	turn func(ctx context.Context, chatReq *chatCompletionRequest) (*chatTurn, error),
+) (*domain.Completion, error) {
+	completion := &domain.Completion{}
+	history := *chat
+	history.Messages = slices.Clone(chat.Messages)
+
+	for round := 0; ; round++ {
+		chatReq, err := newChatCompletionRequest(&history)
+		if err != nil {
+			return nil, err
+		}
+		// Choices would call different tools, so alternatives are requested without them.
+		if c.tools != nil && round < maxToolRounds && familyOf(chat.Model).tools && chat.Choices < 2 {
+			chatReq.Tools = newChatTools(c.tools.Definitions())
+		}
+
+		resp, err := turn(ctx, chatReq)
+		if err != nil {
+			return nil, err
+		}
+
+		usage := resp.usage.toDomain()
+		completion.Usage.PromptTokens += usage.PromptTokens
+		completion.Usage.CompletionTokens += usage.CompletionTokens
+
+		if len(resp.toolCalls) == 0 {
+			if resp.content == "" {
+				return nil, errors.New("no content returned in response")
+			}
+			completion.Message = domain.Message{
+				Role:         resp.role,
+				ContentParts: []domain.ContentPart{{Type: domain.ContentPartTypeText, Data: resp.content}},
+			}
+			if len(resp.alternatives) > 1 {
+				completion.Alternatives = lo.Map(resp.alternatives, func(content string, _ int) domain.Message {
+					return domain.Message{
+						Role:         resp.role,
+						ContentParts: []domain.ContentPart{{Type: domain.ContentPartTypeText, Data: content}},
+					}
+				})
+			}
+			return completion, nil
+		}
+
+		if round >= maxToolRounds {
+			return nil, fmt.Errorf("model still calls tools after %d rounds", maxToolRounds)
+		}
+
+		steps := c.runTools(ctx, resp)
+		history.Messages = append(history.Messages, steps...)
+		completion.Steps = append(completion.Steps, steps...)
+	}
+}
+
+// runTools executes the requested tools and returns the assistant request followed by the results.
+// Tool failures are reported back to the model instead of aborting the answer.
+func (c *client) runTools(ctx context.Context, resp *chatTurn) []domain.Message {
+	request := domain.Message{Role: domain.MessageRoleAssistant}
+	if resp.content != "" {
+		request.ContentParts = []domain.ContentPart{{Type: domain.ContentPartTypeText, Data: resp.content}}
+	}
+
+	results := make([]domain.Message, 0, len(resp.toolCalls))
+	for _, call := range resp.toolCalls {
+		request.ToolCalls = append(request.ToolCalls, domain.ToolCall{
+			ID:        call.ID,
+			Name:      call.Function.Name,
+			Arguments: call.Function.Arguments,
+		})
+
+		slog.InfoContext(ctx, "Calling tool", "name", call.Function.Name, "arguments", call.Function.Arguments)
+
+		var result string
+		if c.tools == nil {
+			result = "error: tools are not available"
+		} else if out, err := c.tools.Call(ctx, call.Function.Name, call.Function.Arguments); err != nil {
+			slog.WarnContext(ctx, "Tool failed", "name", call.Function.Name, "error", err)
+			result = "error: " + err.Error()
+		} else {
+			result = out
+		}
+
+		results = append(results, domain.Message{
+			Role:         domain.MessageRoleTool,
+			ToolCallID:   call.ID,
+			ContentParts: []domain.ContentPart{{Type: domain.ContentPartTypeText, Data: result}},
+		})
+	}
+
+	return append([]domain.Message{request}, results...)
+}
+
+func (c *client) createTurn(ctx context.Context, chatReq *chatCompletionRequest) (*chatTurn, error) {
+	reqBody, err := json.Marshal(chatReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint(c.chatBaseURL, apiPathChatCompletions), bytes.NewBuffer(reqBody))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create HTTP request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	respBody, err := c.doRequest(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send chat completion request: %w", err)
+	}
+
+	var parsedResp chatCompletionResponse
+	if err := json.Unmarshal(respBody, &parsedResp); err != nil {
+		return nil, fmt.Errorf("failed to parse chat completion response: %w", err)
+	}
+
+	if len(parsedResp.Choices) == 0 {
+		return nil, errors.New("no choices returned in response")
+	}
+
+	msg := parsedResp.Choices[0].Message
+
+	var content string
+	if msg.Content != nil {
+		content = fmt.Sprint(msg.Content)
+	}
+
+	var alternatives []string
+	if len(parsedResp.Choices) > 1 {
+		for _, choice := range parsedResp.Choices {
+			if choice.Message.Content != nil {
+				alternatives = append(alternatives, fmt.Sprint(choice.Message.Content))
+			}
+		}
+	}
+
+	return &chatTurn{
+		role:         msg.Role,
+		content:      content,
+		toolCalls:    msg.ToolCalls,
+		usage:        parsedResp.Usage,
+		alternatives: alternatives,
+	}, nil
+}
+
+func (c *client) streamTurn(ctx context.Context, chatReq *chatCompletionRequest, onDelta func(delta string)) (*chatTurn, error) {
+	chatReq.Stream = true
+	chatReq.StreamOptions = &chatStreamOptions{IncludeUsage: true}
+
+	reqBody, err := json.Marshal(chatReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint(c.chatBaseURL, apiPathChatCompletions), bytes.NewBuffer(reqBody))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create HTTP request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "text/event-stream")
+
+	resp, err := c.do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send chat completion request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	turn := &chatTurn{role: chatMessageRoleAssistant}
+	var content strings.Builder
+
+	err = sse.Read(resp.Body, func(event sse.Event) error {
+		if event.Data == streamDoneMarker {
+			return io.EOF
+		}
+
+		var chunk chatCompletionStreamResponse
+		if err := json.Unmarshal([]byte(event.Data), &chunk); err != nil {
+			return fmt.Errorf("failed to parse chat completion chunk: %w", err)
+		}
+
+		if chunk.Usage != nil {
+			turn.usage = chunk.Usage // sent in the last chunk, which has no choices
+		}
+
+		for _, choice := range chunk.Choices {
+			if choice.Delta.Role != "" {
+				turn.role = choice.Delta.Role
+			}
+			if choice.Delta.Content != "" {
+				content.WriteString(choice.Delta.Content)
+				onDelta(choice.Delta.Content)
+			}
+			turn.toolCalls = mergeToolCallDeltas(turn.toolCalls, choice.Delta.ToolCalls)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read chat completion stream: %w", err)
+	}
+
+	turn.content = content.String()
+
+	return turn, nil
+}
+
+// mergeToolCallDeltas assembles streamed tool calls. The first delta of a call carries its id and
+// name, the following ones carry pieces of the arguments; all of them share the call index.
+func mergeToolCallDeltas(calls []chatToolCall, deltas []chatToolCallDelta) []chatToolCall {
+	for _, d := range deltas {
+		for len(calls) <= d.Index {
+			calls = append(calls, chatToolCall{Type: chatToolTypeFunction})
+		}
+		call := &calls[d.Index]
+		if d.ID != "" {
+			call.ID = d.ID
+		}
+		call.Function.Name += d.Function.Name
+		call.Function.Arguments += d.Function.Arguments
+	}
+	return calls
+}
+
+func newChatCompletionRequest(chat *domain.Chat) (*chatCompletionRequest, error) {
+	family := familyOf(chat.Model)
+	messages := make([]chatCompletionMessage, 0, len(chat.Messages)+1)
+
+	if chat.SystemPrompt != "" {
+		messages = append(messages, chatCompletionMessage{
+			Role:    lo.CoalesceOrEmpty(family.systemRole, domain.MessageRoleUser),
+			Content: []chatMessagePart{{Type: chatMessagePartTypeText, Text: chat.SystemPrompt}},
+		})
+	}
+
+	for _, msg := range chat.Messages {
+		switch {
+		case len(msg.ToolCalls) > 0:
+			// Assistant asking for tools
+			calls := make([]chatToolCall, 0, len(msg.ToolCalls))
+			for _, call := range msg.ToolCalls {
+				calls = append(calls, chatToolCall{
+					ID:       call.ID,
+					Type:     chatToolTypeFunction,
+					Function: chatToolCallFunction{Name: call.Name, Arguments: call.Arguments},
+				})
+			}
+			var content any
+			if len(msg.ContentParts) > 0 {
+				content = msg.ContentParts[0].Data
+			}
+			messages = append(messages, chatCompletionMessage{Role: msg.Role, Content: content, ToolCalls: calls})
+		case msg.Role == domain.MessageRoleTool:
+			messages = append(messages, chatCompletionMessage{
+				Role:       msg.Role,
+				Content:    msg.ContentParts[0].Data,
+				ToolCallID: msg.ToolCallID,
+			})
+		case len(msg.ContentParts) == 1 && msg.ContentParts[0].Type == domain.ContentPartTypeText:
+			// Simple text-only case
+			messages = append(messages, chatCompletionMessage{Role: msg.Role, Content: msg.ContentParts[0].Data})
+		default:
+			// Complex content case (multiple parts)
+			var parts []chatMessagePart
+			for _, content := range msg.ContentParts {
+				switch content.Type {
+				case domain.ContentPartTypeText:
+					parts = append(parts, chatMessagePart{Type: chatMessagePartTypeText, Text: content.Data})
+				case domain.ContentPartTypeImage:
+					parts = append(parts, chatMessagePart{
+						Type:     chatMessagePartTypeImageURL,
+						ImageURL: &chatMessageImageURL{URL: content.Data},
+					})
+				default:
+					return nil, errors.New("unsupported content type")
+				}
+			}
+			messages = append(messages, chatCompletionMessage{Role: msg.Role, Content: parts})
+		}
+	}
+
+	chatReq := &chatCompletionRequest{
+		Model:    chat.Model,
+		Messages: messages,
+	}
+
+	if family.reasoning {
+		chatReq.MaxCompletionTokens = lo.CoalesceOrEmpty(chat.Params.MaxTokens, defaultMaxCompletionTokens)
+	} else {
+		// Reasoning models only accept the default sampling.
+		chatReq.MaxTokens = lo.CoalesceOrEmpty(chat.Params.MaxTokens, defaultMaxTokens)
+		chatReq.Temperature = chat.Params.Temperature
+		chatReq.TopP = chat.Params.TopP
+		chatReq.PresencePenalty = chat.Params.PresencePenalty
+		chatReq.FrequencyPenalty = chat.Params.FrequencyPenalty
+	}
+	chatReq.Seed = chat.Params.Seed
+	if chat.Choices > 1 {
+		chatReq.N = chat.Choices
+	}
+	if family.reasoningEffort {
+		chatReq.ReasoningEffort = string(chat.ReasoningEffort)
+	}
+
+	return chatReq, nil
+}
+
+func newChatTools(defs []domain.ToolDefinition) []chatTool {
+	tools := make([]chatTool, 0, len(defs))
+	for _, def := range defs {
+		tools = append(tools, chatTool{
+			Type: chatToolTypeFunction,
+			Function: chatToolFunction{
+				Name:        def.Name,
+				Description: def.Description,
+				Parameters:  def.Parameters,
+			},
+		})
+	}
+	return tools
+}
@@ -7,121 +7,94 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"math"
 	"mime/multipart"
 	"net/http"
-	"os"
+	"net/url"
+	"strings"
 
 	"github.com/dskvich/chatgpt-telegram-bot/pkg/domain"
+	"github.com/samber/lo"
 )
 
 const (
-	apiURLChatCompletions = "https://api.openai.com/v1/chat/completions"
-	apiURLAudioTranscribe = "https://api.openai.com/v1/audio/transcriptions"
-	apiURLImageGeneration = "https://api.openai.com/v1/images/generations"
+	DefaultBaseURL = "https://api.openai.com/v1"
+
+	apiPathChatCompletions = "/chat/completions"
+	apiPathAudioTranscribe = "/audio/transcriptions"
+	apiPathAudioTranslate  = "/audio/translations"
+	apiPathAudioSpeech     = "/audio/speech"
+	apiPathImageGeneration = "/images/generations"
 
-	modelWhisper       = "whisper-1"
 	defaultMaxTokens   = 4096
 	defaultResponseFmt = "b64_json"
+
+	streamDoneMarker = "[DONE]"
 )
 
+// Config describes how to reach an OpenAI-compatible API. The chat, audio and image base URLs
+// fall back to BaseURL, so a single self-hosted server or gateway can be set with one option.
+type Config struct {
+	Token        string
+	BaseURL      string
+	ChatBaseURL  string
+	AudioBaseURL string
+	ImageBaseURL string
+	Headers      map[string]string
+	MaxRetries   int
+	Tools        ToolExecutor // optional
+	HTTPClient   *http.Client // optional, a client without timeouts by default
+}
+
 type client struct {
-	token string
-	hc    *http.Client
+	token        string
+	chatBaseURL  string
+	audioBaseURL string
+	imageBaseURL string
+	headers      map[string]string
+	maxRetries   int
+	tools        ToolExecutor
+	hc           *http.Client
 }
 
-func NewClient(token string) (*client, error) {
-	if token == "" {
+func NewClient(cfg Config) (*client, error) {
+	if cfg.Token == "" {
 		return nil, errors.New("token cannot be empty")
 	}
-	return &client{
-		token: token,
-		hc:    &http.Client{},
-	}, nil
-}
 
-func (c *client) CreateChatCompletion(ctx context.Context, chat *domain.Chat) (*domain.Message, error) {
-	messages := make([]chatCompletionMessage, 0, len(chat.Messages)+1)
-
-	if chat.SystemPrompt != "" {
-		messages = append(messages, chatCompletionMessage{
-			Role:    chatMessageRoleDeveloper,
-			Content: []chatMessagePart{{Type: chatMessagePartTypeText, Text: chat.SystemPrompt}},
-		})
-	}
-
-	for _, msg := range chat.Messages {
-		if len(msg.ContentParts) == 1 && msg.ContentParts[0].Type == domain.ContentPartTypeText {
-			// Simple text-only case
-			messages = append(messages, chatCompletionMessage{Role: msg.Role, Content: msg.ContentParts[0].Data})
-		} else {
-			// Complex content case (multiple parts)
-			var parts []chatMessagePart
-			for _, content := range msg.ContentParts {
-				switch content.Type {
-				case domain.ContentPartTypeText:
-					parts = append(parts, chatMessagePart{Type: chatMessagePartTypeText, Text: content.Data})
-				case domain.ContentPartTypeImage:
-					parts = append(parts, chatMessagePart{
-						Type:     chatMessagePartTypeImageURL,
-						ImageURL: &chatMessageImageURL{URL: content.Data},
-					})
-				default:
-					return nil, errors.New("unsupported content type")
-				}
-			}
-			messages = append(messages, chatCompletionMessage{Role: msg.Role, Content: parts})
-		}
-	}
+	baseURL := lo.CoalesceOrEmpty(cfg.BaseURL, DefaultBaseURL)
 
-	reqBody, err := json.Marshal(chatCompletionRequest{
-		Model:     chat.Model,
-		Messages:  messages,
-		MaxTokens: defaultMaxTokens,
-	})
-	if err != nil {
-		return nil, fmt.Errorf("failed to marshal request: %w", err)
-	}
-
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, apiURLChatCompletions, bytes.NewBuffer(reqBody))
-	if err != nil {
-		return nil, fmt.Errorf("failed to create HTTP request: %w", err)
+	c := &client{
+		token:        cfg.Token,
+		chatBaseURL:  lo.CoalesceOrEmpty(cfg.ChatBaseURL, baseURL),
+		audioBaseURL: lo.CoalesceOrEmpty(cfg.AudioBaseURL, baseURL),
+		imageBaseURL: lo.CoalesceOrEmpty(cfg.ImageBaseURL, baseURL),
+		headers:      cfg.Headers,
+		maxRetries:   max(cfg.MaxRetries, 0),
+		tools:        cfg.Tools,
+		hc:           lo.CoalesceOrEmpty(cfg.HTTPClient, &http.Client{}),
 	}
-	req.Header.Set("Content-Type", "application/json")
 
-	respBody, err := c.doRequest(req)
-	if err != nil {
-		return nil, fmt.Errorf("failed to send chat completion request: %w", err)
-	}
-
-	var parsedResp chatCompletionResponse
-	if err := json.Unmarshal(respBody, &parsedResp); err != nil {
-		return nil, fmt.Errorf("failed to parse chat completion response: %w", err)
+	for _, u := range []string{c.chatBaseURL, c.audioBaseURL, c.imageBaseURL} {
+		if _, err := url.ParseRequestURI(u); err != nil {
+			return nil, fmt.Errorf("invalid base url %q: %w", u, err)
+		}
 	}
 
-	if len(parsedResp.Choices) == 0 {
-		return nil, errors.New("no choices returned in response")
-	}
+	return c, nil
+}
 
-	return &domain.Message{
-		Role:         parsedResp.Choices[0].Message.Role,
-		ContentParts: []domain.ContentPart{{Type: "text", Data: fmt.Sprint(parsedResp.Choices[0].Message.Content)}},
-	}, nil
+func endpoint(baseURL, path string) string {
+	return strings.TrimRight(baseURL, "/") + path
 }
 
 func (c *client) doRequest(req *http.Request) ([]byte, error) {
-	req.Header.Set("Authorization", "Bearer "+c.token)
-
-	resp, err := c.hc.Do(req)
+	resp, err := c.do(req)
 	if err != nil {
-		return nil, fmt.Errorf("HTTP request failed: %w", err)
+		return nil, err
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		respBody, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("unexpected status code: %d, response: %s", resp.StatusCode, string(respBody))
-	}
-
 	respBody, err := io.ReadAll(resp.Body)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read response body: %w", err)
@@ -130,76 +103,130 @@ func (c *client) doRequest(req *http.Request) ([]byte, error) {
 	return respBody, nil
 }
 
-func (c *client) TranscribeAudio(ctx context.Context, audioFilePath string) (string, error) {
-	body, contentType, err := createMultipartForm(audioFilePath, modelWhisper)
-	if err != nil {
-		return "", fmt.Errorf("failed to create multipart form: %w", err)
+// do sends the request and returns the response with an unread body. Transient failures are
+// retried, non-2xx responses are consumed, closed and reported as an error.
+func (c *client) do(req *http.Request) (*http.Response, error) {
+	req.Header.Set("Authorization", "Bearer "+c.token)
+	for k, v := range c.headers {
+		req.Header.Set(k, v)
 	}
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, apiURLAudioTranscribe, body)
+	return c.doWithRetry(req)
+}
+
+// TranscribeAudio recognizes speech in the recording. The file name tells the endpoint the format,
+// the audio is streamed into the request as it is read.
+func (c *client) TranscribeAudio(ctx context.Context, audio io.Reader, fileName string) (domain.Transcript, error) {
+	return c.recognizeSpeech(ctx, apiPathAudioTranscribe, audio, fileName)
+}
+
+// TranslateAudio recognizes speech in any language and returns it translated into English.
+func (c *client) TranslateAudio(ctx context.Context, audio io.Reader, fileName string) (domain.Transcript, error) {
+	return c.recognizeSpeech(ctx, apiPathAudioTranslate, audio, fileName)
+}
+
+func (c *client) recognizeSpeech(ctx context.Context, apiPath string, audio io.Reader, fileName string) (domain.Transcript, error) {
+	body, bodyWriter := io.Pipe()
+	form := multipart.NewWriter(bodyWriter)
+
+	go func() {
+		bodyWriter.CloseWithError(writeAudioForm(form, audio, fileName))
+	}()
+
+	// The audio is streamed into the request rather than held in memory. Such a body cannot be
+	// replayed, so failed requests are not retried.
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint(c.audioBaseURL, apiPath), body)
 	if err != nil {
-		return "", fmt.Errorf("failed to create HTTP request: %w", err)
+		body.Close()
+		return domain.Transcript{}, fmt.Errorf("failed to create HTTP request: %w", err)
 	}
-	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("Content-Type", form.FormDataContentType())
 
 	respBody, err := c.doRequest(req)
 	if err != nil {
-		return "", fmt.Errorf("failed to transcribe audio: %w", err)
+		return domain.Transcript{}, fmt.Errorf("failed to transcribe audio: %w", err)
 	}
 
 	var parsedResp struct {
-		Text string `json:"text"`
+		Text     string  `json:"text"`
+		Duration float64 `json:"duration"`
 	}
 
 	if err := json.Unmarshal(respBody, &parsedResp); err != nil {
-		return "", fmt.Errorf("failed to parse transcription response: %w", err)
+		return domain.Transcript{}, fmt.Errorf("failed to parse transcription response: %w", err)
 	}
 
-	return parsedResp.Text, nil
+	return domain.Transcript{
+		Text:     parsedResp.Text,
+		Duration: int(math.Ceil(parsedResp.Duration)),
+	}, nil
 }
 
-func createMultipartForm(filePath, model string) (*bytes.Buffer, string, error) {
-	file, err := os.Open(filePath)
+// writeAudioForm writes the multipart form of a transcription request. verbose_json adds the
+// duration of the recording to the response.
+func writeAudioForm(form *multipart.Writer, audio io.Reader, fileName string) error {
+	if err := form.WriteField("model", domain.Whisper1Model); err != nil {
+		return fmt.Errorf("failed to write model field: %w", err)
+	}
+	if err := form.WriteField("response_format", "verbose_json"); err != nil {
+		return fmt.Errorf("failed to write response format field: %w", err)
+	}
+
+	fileWriter, err := form.CreateFormFile("file", fileName)
 	if err != nil {
-		return nil, "", fmt.Errorf("failed to open file: %w", err)
+		return fmt.Errorf("failed to create form file: %w", err)
+	}
+	if _, err := io.Copy(fileWriter, audio); err != nil {
+		return fmt.Errorf("failed to copy audio: %w", err)
 	}
-	defer file.Close()
 
-	var body bytes.Buffer
-	writer := multipart.NewWriter(&body)
+	return form.Close()
+}
 
-	fileWriter, err := writer.CreateFormFile("file", filePath)
+// SynthesizeSpeech reads the text aloud and returns the audio as MP3.
+func (c *client) SynthesizeSpeech(ctx context.Context, text, voice string) ([]byte, error) {
+	reqBody, err := json.Marshal(map[string]interface{}{
+		"model":           domain.TTS1Model,
+		"input":           text,
+		"voice":           voice,
+		"response_format": "mp3",
+	})
 	if err != nil {
-		return nil, "", fmt.Errorf("failed to create form file: %w", err)
-	}
-	if _, err := io.Copy(fileWriter, file); err != nil {
-		return nil, "", fmt.Errorf("failed to copy file: %w", err)
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
-	if err := writer.WriteField("model", model); err != nil {
-		return nil, "", fmt.Errorf("failed to write model field: %w", err)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint(c.audioBaseURL, apiPathAudioSpeech), bytes.NewBuffer(reqBody))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create HTTP request: %w", err)
 	}
+	req.Header.Set("Content-Type", "application/json")
 
-	if err := writer.Close(); err != nil {
-		return nil, "", fmt.Errorf("failed to close writer: %w", err)
+	audio, err := c.doRequest(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to synthesize speech: %w", err)
 	}
 
-	return &body, writer.FormDataContentType(), nil
+	return audio, nil
 }
 
-func (c *client) GenerateImage(ctx context.Context, prompt string) ([]byte, error) {
-	reqBody, err := json.Marshal(map[string]interface{}{
-		"model":           domain.DallE2,
+func (c *client) GenerateImage(ctx context.Context, prompt string, settings domain.ImageSettings) ([]byte, error) {
+	params := map[string]interface{}{
+		"model":           settings.Model,
 		"prompt":          prompt,
 		"n":               1,
-		"size":            domain.Size256x256,
+		"size":            settings.Size,
 		"response_format": defaultResponseFmt,
-	})
+	}
+	if settings.Quality != "" {
+		params["quality"] = settings.Quality
+	}
+
+	reqBody, err := json.Marshal(params)
 	if err != nil {
 		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, apiURLImageGeneration, bytes.NewBuffer(reqBody))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint(c.imageBaseURL, apiPathImageGeneration), bytes.NewBuffer(reqBody))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create HTTP request: %w", err)
 	}
@@ -210,19 +237,5 @@ func (c *client) GenerateImage(ctx context.Context, prompt string) ([]byte, erro
 		return nil, fmt.Errorf("failed to generate image: %w", err)
 	}
 
-	var parsedResp struct {
-		Data []struct {
-			B64Json []byte `json:"b64_json"`
-		} `json:"data"`
-	}
-
-	if err := json.Unmarshal(respBody, &parsedResp); err != nil {
-		return nil, fmt.Errorf("failed to parse image generation response: %w", err)
-	}
-
-	if len(parsedResp.Data) == 0 {
-		return nil, errors.New("no image data returned")
-	}
-
-	return parsedResp.Data[0].B64Json, nil
+	return parseImageResponse(respBody)
 }
@@ -0,0 +1,86 @@
+package openai
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+
+	"github.com/dskvich/chatgpt-telegram-bot/pkg/domain"
+)
+
+const (
+	apiPathEmbeddings = "/embeddings"
+
+	// embeddingsBatchSize keeps every request well below the limit of tokens per request.
+	embeddingsBatchSize = 100
+)
+
+// CreateEmbeddings returns an embedding for every input, in the order of the inputs.
+// Long lists are sent in several requests.
+func (c *client) CreateEmbeddings(ctx context.Context, inputs []string) ([][]float32, domain.Usage, error) {
+	var (
+		embeddings = make([][]float32, 0, len(inputs))
+		usage      domain.Usage
+	)
+
+	for batch := range slices.Chunk(inputs, embeddingsBatchSize) {
+		res, batchUsage, err := c.createEmbeddings(ctx, batch)
+		if err != nil {
+			return nil, usage, err
+		}
+		embeddings = append(embeddings, res...)
+		usage.PromptTokens += batchUsage.PromptTokens
+	}
+
+	return embeddings, usage, nil
+}
+
+func (c *client) createEmbeddings(ctx context.Context, inputs []string) ([][]float32, domain.Usage, error) {
+	reqBody, err := json.Marshal(map[string]interface{}{
+		"model": domain.TextEmbedding3SmallModel,
+		"input": inputs,
+	})
+	if err != nil {
+		return nil, domain.Usage{}, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint(c.chatBaseURL, apiPathEmbeddings), bytes.NewBuffer(reqBody))
+	if err != nil {
+		return nil, domain.Usage{}, fmt.Errorf("failed to create HTTP request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	respBody, err := c.doRequest(req)
+	if err != nil {
+		return nil, domain.Usage{}, fmt.Errorf("failed to create embeddings: %w", err)
+	}
+
+	var parsedResp struct {
+		Data []struct {
+			Index     int       `json:"index"`
+			Embedding []float32 `json:"embedding"`
+		} `json:"data"`
+		Usage *chatCompletionUsage `json:"usage,omitempty"`
+	}
+
+	if err := json.Unmarshal(respBody, &parsedResp); err != nil {
+		return nil, domain.Usage{}, fmt.Errorf("failed to parse embeddings response: %w", err)
+	}
+
+	if len(parsedResp.Data) != len(inputs) {
+		return nil, domain.Usage{}, fmt.Errorf("got %d embeddings for %d inputs", len(parsedResp.Data), len(inputs))
+	}
+
+	embeddings := make([][]float32, len(inputs))
+	for _, d := range parsedResp.Data {
+		if d.Index < 0 || d.Index >= len(inputs) {
+			return nil, domain.Usage{}, fmt.Errorf("unexpected embedding index %d", d.Index)
+		}
+		embeddings[d.Index] = d.Embedding
+	}
+
+	return embeddings, parsedResp.Usage.toDomain(), nil
+}
@@ -0,0 +1,101 @@
+package openai
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/dskvich/chatgpt-telegram-bot/pkg/domain"
+)
+
+const (
+	errCodeInsufficientQuota      = "insufficient_quota"
+	errCodeContextLengthExceeded  = "context_length_exceeded"
+	errCodeContentPolicyViolation = "content_policy_violation"
+	errCodeContentFilter          = "content_filter"
+	errCodeModelNotFound          = "model_not_found"
+	errCodeInvalidAPIKey          = "invalid_api_key"
+)
+
+// APIError is a non-2xx response of the API. It unwraps to one of the domain errors when the
+// failure is one the user can act on.
+type APIError struct {
+	StatusCode int
+	Type       string
+	Code       string
+	Param      string
+	Message    string
+	Body       string
+
+	kind error
+}
+
+func (e *APIError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("unexpected status code: %d, response: %s", e.StatusCode, e.Body)
+	}
+	return fmt.Sprintf("status code %d, type %q, code %q: %s", e.StatusCode, e.Type, e.Code, e.Message)
+}
+
+func (e *APIError) Unwrap() error {
+	return e.kind
+}
+
+// newAPIError parses the error envelope of the response; bodies that are not in the OpenAI
+// format are kept as is, so gateways and compatible servers still produce a readable error.
+func newAPIError(statusCode int, body []byte) *APIError {
+	var envelope struct {
+		Error struct {
+			Message string `json:"message"`
+			Type    string `json:"type"`
+			Param   any    `json:"param"`
+			Code    any    `json:"code"`
+		} `json:"error"`
+	}
+	_ = json.Unmarshal(body, &envelope)
+
+	e := &APIError{
+		StatusCode: statusCode,
+		Type:       envelope.Error.Type,
+		Message:    envelope.Error.Message,
+		Body:       string(body),
+	}
+	// Code and param are strings, but some compatible servers send numbers or null.
+	if envelope.Error.Code != nil {
+		e.Code = fmt.Sprint(envelope.Error.Code)
+	}
+	if envelope.Error.Param != nil {
+		e.Param = fmt.Sprint(envelope.Error.Param)
+	}
+	e.kind = classifyAPIError(e)
+
+	return e
+}
+
+func classifyAPIError(e *APIError) error {
+	switch e.Code {
+	case errCodeInsufficientQuota:
+		return domain.ErrInsufficientQuota
+	case errCodeContextLengthExceeded:
+		return domain.ErrContextLengthExceeded
+	case errCodeContentPolicyViolation, errCodeContentFilter:
+		return domain.ErrContentPolicy
+	case errCodeModelNotFound:
+		return domain.ErrInvalidModel
+	case errCodeInvalidAPIKey:
+		return domain.ErrUnauthorized
+	}
+
+	switch {
+	case e.Type == errCodeInsufficientQuota:
+		return domain.ErrInsufficientQuota
+	case e.StatusCode == http.StatusUnauthorized:
+		return domain.ErrUnauthorized
+	case e.StatusCode == http.StatusTooManyRequests:
+		return domain.ErrRateLimited
+	case e.StatusCode == http.StatusNotFound && e.Param == "model":
+		return domain.ErrInvalidModel
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,41 @@
+package openai
+
+import (
+	"strings"
+
+	"github.com/dskvich/chatgpt-telegram-bot/pkg/llm"
+)
+
+const defaultMaxCompletionTokens = llm.ReasoningOutputTokens
+
+// modelFamily describes which request parameters a group of models understands.
+type modelFamily struct {
+	// reasoning models spend hidden tokens on thinking, so their limit is set with
+	// max_completion_tokens and has to leave room for the reasoning.
+	reasoning bool
+	// reasoningEffort is accepted by the model.
+	reasoningEffort bool
+	// systemRole carries the system prompt; empty when the model has no system messages
+	// and the prompt is sent as the first user message instead.
+	systemRole string
+	tools      bool
+}
+
+var (
+	legacyFamily = modelFamily{systemRole: chatMessageRoleSystem, tools: true}
+	// o1-mini and o1-preview predate developer messages, reasoning effort and tools.
+	earlyReasoningFamily = modelFamily{reasoning: true}
+	reasoningFamily      = modelFamily{reasoning: true, reasoningEffort: true, systemRole: chatMessageRoleDeveloper, tools: true}
+)
+
+func familyOf(model string) modelFamily {
+	switch {
+	case strings.HasPrefix(model, "o1-mini"), strings.HasPrefix(model, "o1-preview"):
+		return earlyReasoningFamily
+	case llm.IsReasoningModel(model):
+		return reasoningFamily
+	default:
+		// The system role is understood by every OpenAI model and by compatible servers.
+		return legacyFamily
+	}
+}
@@ -0,0 +1,192 @@
+package openai
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	_ "image/gif"  // register decoders for image.Decode
+	_ "image/jpeg" // register decoders for image.Decode
+	"image/png"
+	"maps"
+	"mime/multipart"
+	"net/http"
+
+	"github.com/dskvich/chatgpt-telegram-bot/pkg/domain"
+)
+
+const (
+	apiPathImageEdits      = "/images/edits"
+	apiPathImageVariations = "/images/variations"
+
+	// Edits and variations accept a square PNG of less than 4 MB. A noisy photo may not fit at
+	// maxSourceImageSide, then it is sent at fallbackImageSide.
+	maxSourceImageSide  = 1024
+	fallbackImageSide   = 512
+	maxSourceImageBytes = 4 << 20
+)
+
+// EditImage changes the source image according to the prompt. dall-e-2 only redraws the transparent
+// parts of an image, and a Telegram photo has none, so the whole image is marked editable with a mask.
+func (c *client) EditImage(ctx context.Context, source []byte, prompt string, settings domain.ImageSettings) ([]byte, error) {
+	return c.transformImage(ctx, apiPathImageEdits, source, true, map[string]string{"prompt": prompt}, settings)
+}
+
+// CreateImageVariation generates a variation of the source image.
+func (c *client) CreateImageVariation(ctx context.Context, source []byte, settings domain.ImageSettings) ([]byte, error) {
+	return c.transformImage(ctx, apiPathImageVariations, source, false, nil, settings)
+}
+
+func (c *client) transformImage(
+	ctx context.Context,
+	path string,
+	source []byte,
+	withMask bool,
+	fields map[string]string,
+	settings domain.ImageSettings,
+) ([]byte, error) {
+	pngData, side, err := toSquarePNG(source)
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare source image: %w", err)
+	}
+
+	files := map[string][]byte{"image": pngData}
+	if withMask {
+		if files["mask"], err = transparentPNG(side); err != nil {
+			return nil, fmt.Errorf("failed to prepare mask: %w", err)
+		}
+	}
+
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+
+	for name, data := range files {
+		fileWriter, err := writer.CreateFormFile(name, name+".png")
+		if err != nil {
+			return nil, fmt.Errorf("failed to create form file: %w", err)
+		}
+		if _, err := fileWriter.Write(data); err != nil {
+			return nil, fmt.Errorf("failed to write %s: %w", name, err)
+		}
+	}
+
+	params := map[string]string{
+		"model":           string(settings.Model),
+		"n":               "1",
+		"size":            string(settings.Size),
+		"response_format": defaultResponseFmt,
+	}
+	maps.Copy(params, fields)
+
+	for k, v := range params {
+		if err := writer.WriteField(k, v); err != nil {
+			return nil, fmt.Errorf("failed to write %s field: %w", k, err)
+		}
+	}
+
+	if err := writer.Close(); err != nil {
+		return nil, fmt.Errorf("failed to close writer: %w", err)
+	}
+
+	// A bytes.Reader lets the request be replayed on retry.
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint(c.imageBaseURL, path), bytes.NewReader(body.Bytes()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create HTTP request: %w", err)
+	}
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+
+	respBody, err := c.doRequest(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to transform image: %w", err)
+	}
+
+	return parseImageResponse(respBody)
+}
+
+func parseImageResponse(respBody []byte) ([]byte, error) {
+	var parsedResp struct {
+		Data []struct {
+			B64Json []byte `json:"b64_json"`
+		} `json:"data"`
+	}
+
+	if err := json.Unmarshal(respBody, &parsedResp); err != nil {
+		return nil, fmt.Errorf("failed to parse image response: %w", err)
+	}
+
+	if len(parsedResp.Data) == 0 {
+		return nil, errors.New("no image data returned")
+	}
+
+	return parsedResp.Data[0].B64Json, nil
+}
+
+// toSquarePNG crops the center square of the image, scales it down to maxSourceImageSide, or to
+// fallbackImageSide when the PNG would be too large, and returns it as an RGBA PNG with its side.
+// dall-e-2 rejects PNGs without an alpha channel.
+func toSquarePNG(data []byte) ([]byte, int, error) {
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, 0, fmt.Errorf("decoding image: %w", err)
+	}
+
+	bounds := src.Bounds()
+	side := min(bounds.Dx(), bounds.Dy())
+	crop := image.Rect(0, 0, side, side).Add(image.Pt(
+		bounds.Min.X+(bounds.Dx()-side)/2,
+		bounds.Min.Y+(bounds.Dy()-side)/2,
+	))
+
+	for _, target := range []int{min(side, maxSourceImageSide), min(side, fallbackImageSide)} {
+		pngData, err := encodeRGBA(scaleSquare(src, crop, target))
+		if err != nil {
+			return nil, 0, err
+		}
+		if len(pngData) < maxSourceImageBytes {
+			return pngData, target, nil
+		}
+	}
+
+	return nil, 0, errors.New("image is too large even when scaled down")
+}
+
+// scaleSquare scales the square crop of the image to the target side.
+func scaleSquare(src image.Image, crop image.Rectangle, target int) *image.NRGBA {
+	side := crop.Dx()
+	dst := image.NewNRGBA(image.Rect(0, 0, target, target))
+	if target == side {
+		draw.Draw(dst, dst.Bounds(), src, crop.Min, draw.Src)
+		return dst
+	}
+
+	// Nearest neighbour is good enough for a source image the model redraws anyway.
+	for y := range target {
+		for x := range target {
+			dst.Set(x, y, src.At(crop.Min.X+x*side/target, crop.Min.Y+y*side/target))
+		}
+	}
+	return dst
+}
+
+// transparentPNG returns a fully transparent square, a mask that lets the edit change the whole image.
+func transparentPNG(side int) ([]byte, error) {
+	return encodeRGBA(image.NewNRGBA(image.Rect(0, 0, side, side)))
+}
+
+// alphaImage makes the PNG encoder keep the alpha channel of an opaque image.
+type alphaImage struct {
+	*image.NRGBA
+}
+
+func (alphaImage) Opaque() bool { return false }
+
+func encodeRGBA(img *image.NRGBA) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, alphaImage{img}); err != nil {
+		return nil, fmt.Errorf("encoding png: %w", err)
+	}
+	return buf.Bytes(), nil
+}
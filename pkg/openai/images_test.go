@@ -0,0 +1,67 @@
+package openai
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"math/rand/v2"
+	"testing"
+)
+
+func TestToSquarePNGKeepsAlphaChannel(t *testing.T) {
+	src := image.NewRGBA(image.Rect(0, 0, 1600, 1200))
+	for y := range 1200 {
+		for x := range 1600 {
+			src.Set(x, y, color.RGBA{R: uint8(x), G: uint8(y), B: 128, A: 255})
+		}
+	}
+
+	var photo bytes.Buffer
+	if err := jpeg.Encode(&photo, src, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	data, side, err := toSquarePNG(photo.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := png.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if cfg.ColorModel != color.NRGBAModel {
+		t.Errorf("color model = %v, want RGBA with alpha", cfg.ColorModel)
+	}
+	if cfg.Width != maxSourceImageSide || cfg.Height != maxSourceImageSide || side != maxSourceImageSide {
+		t.Errorf("size = %dx%d (side %d), want %d", cfg.Width, cfg.Height, side, maxSourceImageSide)
+	}
+}
+
+func TestToSquarePNGScalesDownNoisyPhotos(t *testing.T) {
+	rnd := rand.New(rand.NewPCG(1, 2))
+	src := image.NewNRGBA(image.Rect(0, 0, maxSourceImageSide, maxSourceImageSide))
+	for i := range src.Pix {
+		src.Pix[i] = uint8(rnd.Uint32())
+	}
+
+	var photo bytes.Buffer
+	if err := png.Encode(&photo, src); err != nil {
+		t.Fatal(err)
+	}
+
+	data, side, err := toSquarePNG(photo.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if side != fallbackImageSide {
+		t.Errorf("side = %d, want %d", side, fallbackImageSide)
+	}
+	if len(data) >= maxSourceImageBytes {
+		t.Errorf("png is %d bytes, want less than %d", len(data), maxSourceImageBytes)
+	}
+}
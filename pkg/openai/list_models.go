@@ -0,0 +1,40 @@
+package openai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+const apiPathModels = "/models"
+
+// ListModels returns the ids of the models available to the token.
+func (c *client) ListModels(ctx context.Context) ([]string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint(c.chatBaseURL, apiPathModels), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create HTTP request: %w", err)
+	}
+
+	respBody, err := c.doRequest(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list models: %w", err)
+	}
+
+	var parsedResp struct {
+		Data []struct {
+			ID string `json:"id"`
+		} `json:"data"`
+	}
+
+	if err := json.Unmarshal(respBody, &parsedResp); err != nil {
+		return nil, fmt.Errorf("failed to parse models response: %w", err)
+	}
+
+	models := make([]string, 0, len(parsedResp.Data))
+	for _, m := range parsedResp.Data {
+		models = append(models, m.ID)
+	}
+
+	return models, nil
+}
@@ -1,22 +1,103 @@
 package openai
 
+import (
+	"encoding/json"
+
+	"github.com/dskvich/chatgpt-telegram-bot/pkg/domain"
+)
+
 type chatCompletionRequest struct {
-	Model     string                  `json:"model"`
-	Messages  []chatCompletionMessage `json:"messages"`
-	MaxTokens int                     `json:"max_tokens"`
+	Model               string                  `json:"model"`
+	Messages            []chatCompletionMessage `json:"messages"`
+	MaxTokens           int                     `json:"max_tokens,omitempty"`
+	MaxCompletionTokens int                     `json:"max_completion_tokens,omitempty"`
+	ReasoningEffort     string                  `json:"reasoning_effort,omitempty"`
+	Temperature         *float64                `json:"temperature,omitempty"`
+	TopP                *float64                `json:"top_p,omitempty"`
+	PresencePenalty     *float64                `json:"presence_penalty,omitempty"`
+	FrequencyPenalty    *float64                `json:"frequency_penalty,omitempty"`
+	Seed                *int64                  `json:"seed,omitempty"`
+	N                   int                     `json:"n,omitempty"`
+	Tools               []chatTool              `json:"tools,omitempty"`
+	Stream              bool                    `json:"stream,omitempty"`
+	StreamOptions       *chatStreamOptions      `json:"stream_options,omitempty"`
+}
+
+type chatStreamOptions struct {
+	IncludeUsage bool `json:"include_usage"`
 }
 
 type chatCompletionResponse struct {
 	Choices []chatCompletionChoice `json:"choices"`
+	Usage   *chatCompletionUsage   `json:"usage,omitempty"`
+}
+
+type chatCompletionUsage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+}
+
+func (u *chatCompletionUsage) toDomain() domain.Usage {
+	if u == nil {
+		return domain.Usage{}
+	}
+	return domain.Usage{PromptTokens: u.PromptTokens, CompletionTokens: u.CompletionTokens}
 }
 
 type chatCompletionChoice struct {
 	Message chatCompletionMessage `json:"message"`
 }
 
+type chatCompletionStreamResponse struct {
+	Choices []chatCompletionStreamChoice `json:"choices"`
+	Usage   *chatCompletionUsage         `json:"usage,omitempty"`
+}
+
+type chatCompletionStreamChoice struct {
+	Delta chatCompletionDelta `json:"delta"`
+}
+
+type chatCompletionDelta struct {
+	Role      string              `json:"role,omitempty"`
+	Content   string              `json:"content,omitempty"`
+	ToolCalls []chatToolCallDelta `json:"tool_calls,omitempty"`
+}
+
 type chatCompletionMessage struct {
-	Role    string `json:"role"`
-	Content any    `json:"content"`
+	Role       string         `json:"role"`
+	Content    any            `json:"content"`
+	ToolCalls  []chatToolCall `json:"tool_calls,omitempty"`
+	ToolCallID string         `json:"tool_call_id,omitempty"`
+}
+
+const chatToolTypeFunction = "function"
+
+type chatTool struct {
+	Type     string           `json:"type"`
+	Function chatToolFunction `json:"function"`
+}
+
+type chatToolFunction struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description,omitempty"`
+	Parameters  json.RawMessage `json:"parameters,omitempty"`
+}
+
+type chatToolCall struct {
+	ID       string               `json:"id"`
+	Type     string               `json:"type"`
+	Function chatToolCallFunction `json:"function"`
+}
+
+type chatToolCallFunction struct {
+	Name      string `json:"name"`
+	Arguments string `json:"arguments"`
+}
+
+type chatToolCallDelta struct {
+	Index    int                  `json:"index"`
+	ID       string               `json:"id,omitempty"`
+	Function chatToolCallFunction `json:"function"`
 }
 
 type chatMessagePartType string
@@ -36,4 +117,8 @@ type chatMessageImageURL struct {
 	URL string `json:"url,omitempty"`
 }
 
-const chatMessageRoleDeveloper = "developer"
+const (
+	chatMessageRoleSystem    = "system"
+	chatMessageRoleDeveloper = "developer"
+	chatMessageRoleAssistant = "assistant"
+)
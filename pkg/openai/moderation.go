@@ -0,0 +1,64 @@
+package openai
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/dskvich/chatgpt-telegram-bot/pkg/domain"
+)
+
+const (
+	apiPathModerations = "/moderations"
+
+	moderationModel = "omni-moderation-latest"
+)
+
+// Moderate checks the text against the usage policies.
+func (c *client) Moderate(ctx context.Context, text string) (*domain.ModerationResult, error) {
+	reqBody, err := json.Marshal(map[string]interface{}{
+		"model": moderationModel,
+		"input": text,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint(c.chatBaseURL, apiPathModerations), bytes.NewBuffer(reqBody))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create HTTP request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	respBody, err := c.doRequest(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to moderate text: %w", err)
+	}
+
+	var parsedResp struct {
+		Results []struct {
+			Flagged        bool               `json:"flagged"`
+			Categories     map[string]bool    `json:"categories"`
+			CategoryScores map[string]float64 `json:"category_scores"`
+		} `json:"results"`
+	}
+
+	if err := json.Unmarshal(respBody, &parsedResp); err != nil {
+		return nil, fmt.Errorf("failed to parse moderation response: %w", err)
+	}
+
+	if len(parsedResp.Results) == 0 {
+		return nil, errors.New("no moderation results returned")
+	}
+
+	result := parsedResp.Results[0]
+
+	return &domain.ModerationResult{
+		Flagged:    result.Flagged,
+		Categories: result.Categories,
+		Scores:     result.CategoryScores,
+	}, nil
+}
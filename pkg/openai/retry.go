@@ -0,0 +1,157 @@
+package openai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/dskvich/chatgpt-telegram-bot/pkg/domain"
+)
+
+const (
+	retryBaseDelay = 500 * time.Millisecond
+	retryMaxDelay  = 30 * time.Second
+	retryMaxWait   = time.Minute
+	// maxBackoffShift caps the doubling of the delay: retryBaseDelay<<7 already exceeds retryMaxDelay.
+	maxBackoffShift = 7
+
+	headerRetryAfter             = "Retry-After"
+	headerRateLimitRemainingReqs = "X-Ratelimit-Remaining-Requests"
+	headerRateLimitRemainingToks = "X-Ratelimit-Remaining-Tokens"
+	headerRateLimitResetReqs     = "X-Ratelimit-Reset-Requests"
+	headerRateLimitResetToks     = "X-Ratelimit-Reset-Tokens"
+)
+
+// doWithRetry sends the request, retrying network errors, 429 and 5xx responses with exponential
+// backoff and jitter. Server hints from Retry-After and x-ratelimit-reset-* take precedence over
+// the computed backoff. No retry is attempted if it would not finish before the context deadline,
+// nor for a request whose body cannot be sent again because it has no GetBody.
+func (c *client) doWithRetry(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+
+	for attempt := 0; ; attempt++ {
+		if attempt > 0 {
+			if err := rewindBody(req); err != nil {
+				return nil, err
+			}
+		}
+
+		resp, err := c.hc.Do(req)
+		if err != nil {
+			if ctx.Err() != nil || attempt >= c.maxRetries || req.GetBody == nil && req.Body != nil {
+				return nil, fmt.Errorf("HTTP request failed: %w", err)
+			}
+			if waitErr := waitRetry(ctx, backoff(attempt)); waitErr != nil {
+				return nil, fmt.Errorf("HTTP request failed: %w", err)
+			}
+			continue
+		}
+
+		if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+			return resp, nil
+		}
+
+		respBody, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		statusErr := newAPIError(resp.StatusCode, respBody)
+
+		if !isRetryable(statusErr) || attempt >= c.maxRetries || req.GetBody == nil && req.Body != nil {
+			return nil, statusErr
+		}
+
+		delay, ok := serverDelay(resp.Header)
+		if !ok {
+			delay = backoff(attempt)
+		}
+		if delay > retryMaxWait {
+			return nil, statusErr
+		}
+
+		if err := waitRetry(ctx, delay); err != nil {
+			return nil, statusErr
+		}
+	}
+}
+
+func isRetryable(apiErr *APIError) bool {
+	switch {
+	case apiErr.StatusCode == http.StatusTooManyRequests:
+		// Running out of quota is reported as 429 too, but waiting does not help.
+		return !errors.Is(apiErr, domain.ErrInsufficientQuota)
+	case apiErr.StatusCode >= http.StatusInternalServerError:
+		return true
+	default:
+		return false
+	}
+}
+
+func rewindBody(req *http.Request) error {
+	if req.Body == nil || req.GetBody == nil {
+		return nil
+	}
+
+	body, err := req.GetBody()
+	if err != nil {
+		return fmt.Errorf("failed to rewind request body: %w", err)
+	}
+	req.Body = body
+
+	return nil
+}
+
+// backoff returns a delay in [d/2, d) where d doubles with every attempt up to retryMaxDelay.
+func backoff(attempt int) time.Duration {
+	d := min(retryBaseDelay<<min(attempt, maxBackoffShift), retryMaxDelay)
+	return d/2 + rand.N(d/2)
+}
+
+// serverDelay reads the wait time suggested by the server.
+func serverDelay(h http.Header) (time.Duration, bool) {
+	if v := h.Get(headerRetryAfter); v != "" {
+		if secs, err := strconv.Atoi(v); err == nil {
+			return time.Duration(secs) * time.Second, true
+		}
+		if t, err := http.ParseTime(v); err == nil {
+			return max(time.Until(t), 0), true
+		}
+	}
+
+	// Prefer the reset of the exhausted limit, fall back to the longest reset otherwise.
+	var longest time.Duration
+	for _, pair := range [][2]string{
+		{headerRateLimitRemainingReqs, headerRateLimitResetReqs},
+		{headerRateLimitRemainingToks, headerRateLimitResetToks},
+	} {
+		reset, err := time.ParseDuration(h.Get(pair[1]))
+		if err != nil {
+			continue
+		}
+		if h.Get(pair[0]) == "0" {
+			return reset, true
+		}
+		longest = max(longest, reset)
+	}
+
+	return longest, longest > 0
+}
+
+func waitRetry(ctx context.Context, delay time.Duration) error {
+	if deadline, ok := ctx.Deadline(); ok && time.Now().Add(delay).After(deadline) {
+		return errors.New("retry would exceed context deadline")
+	}
+
+	t := time.NewTimer(delay)
+	defer t.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
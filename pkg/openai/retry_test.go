@@ -0,0 +1,94 @@
+package openai
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestServerDelay(t *testing.T) {
+	tests := []struct {
+		name    string
+		headers map[string]string
+		want    time.Duration
+		wantOK  bool
+	}{
+		{
+			name:    "no hints",
+			headers: nil,
+			wantOK:  false,
+		},
+		{
+			name:    "retry-after in seconds",
+			headers: map[string]string{headerRetryAfter: "7"},
+			want:    7 * time.Second,
+			wantOK:  true,
+		},
+		{
+			name:    "retry-after takes precedence",
+			headers: map[string]string{headerRetryAfter: "2", headerRateLimitResetReqs: "20s"},
+			want:    2 * time.Second,
+			wantOK:  true,
+		},
+		{
+			name: "reset of the exhausted limit",
+			headers: map[string]string{
+				headerRateLimitRemainingReqs: "5",
+				headerRateLimitResetReqs:     "30s",
+				headerRateLimitRemainingToks: "0",
+				headerRateLimitResetToks:     "1.5s",
+			},
+			want:   1500 * time.Millisecond,
+			wantOK: true,
+		},
+		{
+			name: "longest reset when nothing is exhausted",
+			headers: map[string]string{
+				headerRateLimitResetReqs: "500ms",
+				headerRateLimitResetToks: "6m0s",
+			},
+			want:   6 * time.Minute,
+			wantOK: true,
+		},
+		{
+			name:    "unparsable hints",
+			headers: map[string]string{headerRetryAfter: "soon", headerRateLimitResetReqs: "later"},
+			wantOK:  false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := http.Header{}
+			for k, v := range tt.headers {
+				h.Set(k, v)
+			}
+
+			got, ok := serverDelay(h)
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("serverDelay() = %v, %v, want %v, %v", got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	tests := []struct {
+		attempt int
+		ceiling time.Duration
+	}{
+		{0, retryBaseDelay},
+		{1, 2 * retryBaseDelay},
+		{3, 8 * retryBaseDelay},
+		{10, retryMaxDelay},
+		{100, retryMaxDelay},
+	}
+
+	for _, tt := range tests {
+		for range 20 {
+			if got := backoff(tt.attempt); got < tt.ceiling/2 || got >= tt.ceiling {
+				t.Fatalf("backoff(%d) = %v, want in [%v, %v)", tt.attempt, got, tt.ceiling/2, tt.ceiling)
+			}
+		}
+	}
+}
@@ -66,16 +66,17 @@ func (hr *htmlRenderer) ListItem(out *bytes.Buffer, text []byte, flags int) {
 	out.WriteByte('\n')
 }
 
+const markdownExtensions = blackfriday.EXTENSION_NO_INTRA_EMPHASIS |
+	blackfriday.EXTENSION_FENCED_CODE |
+	blackfriday.EXTENSION_AUTOLINK |
+	blackfriday.EXTENSION_SPACE_HEADERS |
+	blackfriday.EXTENSION_HEADER_IDS |
+	blackfriday.EXTENSION_BACKSLASH_LINE_BREAK |
+	blackfriday.EXTENSION_DEFINITION_LISTS
+
 func ToHTML(content string) string {
 	renderer := newHTMLRenderer()
-	extensions := blackfriday.EXTENSION_NO_INTRA_EMPHASIS |
-		blackfriday.EXTENSION_FENCED_CODE |
-		blackfriday.EXTENSION_AUTOLINK |
-		blackfriday.EXTENSION_SPACE_HEADERS |
-		blackfriday.EXTENSION_HEADER_IDS |
-		blackfriday.EXTENSION_BACKSLASH_LINE_BREAK |
-		blackfriday.EXTENSION_DEFINITION_LISTS
-
-	htmlOutput := blackfriday.Markdown([]byte(content), renderer, extensions)
+
+	htmlOutput := blackfriday.Markdown([]byte(content), renderer, markdownExtensions)
 	return string(htmlOutput)
 }
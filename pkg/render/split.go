@@ -0,0 +1,50 @@
+package render
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+const codeFence = "```"
+
+// CloseCodeFences terminates a dangling fenced code block, so partially received markdown
+// still renders as code instead of leaking the fence markers into the text.
+func CloseCodeFences(content string) string {
+	if strings.Count(content, codeFence)%2 == 1 {
+		return content + "\n" + codeFence
+	}
+	return content
+}
+
+// SplitMarkdown cuts content into a head whose rendered HTML fits into limit runes and the
+// remaining tail. Cuts prefer line breaks; a code block that spans the cut is closed in the
+// head and reopened in the tail.
+func SplitMarkdown(content string, limit int) (string, string) {
+	head := content
+	for utf8.RuneCountInString(ToHTML(CloseCodeFences(head))) > limit {
+		cut := strings.LastIndex(strings.TrimRight(head, "\n"), "\n")
+		if cut <= 0 {
+			cut = halfRuneIndex(head)
+		}
+		head = head[:cut]
+	}
+
+	tail := strings.TrimLeft(content[len(head):], "\n")
+	if strings.Count(head, codeFence)%2 == 1 {
+		head += "\n" + codeFence
+		tail = codeFence + "\n" + tail
+	}
+
+	return head, tail
+}
+
+func halfRuneIndex(s string) int {
+	n := utf8.RuneCountInString(s) / 2
+	for i := range s {
+		if n == 0 {
+			return i
+		}
+		n--
+	}
+	return len(s)
+}
@@ -0,0 +1,140 @@
+package render
+
+import (
+	"bytes"
+	"html"
+	"regexp"
+	"strings"
+
+	"github.com/russross/blackfriday"
+)
+
+var blankLinesRe = regexp.MustCompile(`\n{3,}`)
+
+// textRenderer drops the markdown markup and keeps what is meant to be read: emphasis markers,
+// code fences and link targets disappear, the text they wrap stays.
+type textRenderer struct{}
+
+func (textRenderer) BlockCode(out *bytes.Buffer, text []byte, _ string) {
+	out.Write(text)
+	out.WriteString("\n\n")
+}
+
+func (textRenderer) BlockQuote(out *bytes.Buffer, text []byte) {
+	out.Write(text)
+}
+
+func (textRenderer) BlockHtml(*bytes.Buffer, []byte) {}
+
+func (r textRenderer) Header(out *bytes.Buffer, text func() bool, _ int, _ string) {
+	r.Paragraph(out, text)
+}
+
+func (textRenderer) HRule(out *bytes.Buffer) {
+	out.WriteByte('\n')
+}
+
+func (r textRenderer) List(out *bytes.Buffer, text func() bool, _ int) {
+	r.Paragraph(out, text)
+}
+
+func (textRenderer) ListItem(out *bytes.Buffer, text []byte, _ int) {
+	out.Write(bytes.TrimSpace(text))
+	out.WriteByte('\n')
+}
+
+func (textRenderer) Paragraph(out *bytes.Buffer, text func() bool) {
+	marker := out.Len()
+	if !text() {
+		out.Truncate(marker)
+		return
+	}
+	out.WriteString("\n\n")
+}
+
+func (textRenderer) Table(out *bytes.Buffer, header, body []byte, _ []int) {
+	out.Write(header)
+	out.Write(body)
+	out.WriteByte('\n')
+}
+
+func (textRenderer) TableRow(out *bytes.Buffer, text []byte) {
+	out.Write(bytes.TrimSuffix(text, []byte(", ")))
+	out.WriteByte('\n')
+}
+
+func (textRenderer) TableHeaderCell(out *bytes.Buffer, text []byte, _ int) {
+	out.Write(text)
+	out.WriteString(", ")
+}
+
+func (textRenderer) TableCell(out *bytes.Buffer, text []byte, _ int) {
+	out.Write(text)
+	out.WriteString(", ")
+}
+
+func (textRenderer) Footnotes(*bytes.Buffer, func() bool)            {}
+func (textRenderer) FootnoteItem(*bytes.Buffer, []byte, []byte, int) {}
+
+func (textRenderer) TitleBlock(out *bytes.Buffer, text []byte) {
+	out.Write(text)
+}
+
+func (textRenderer) AutoLink(out *bytes.Buffer, link []byte, _ int) {
+	out.Write(link)
+}
+
+func (textRenderer) CodeSpan(out *bytes.Buffer, text []byte) {
+	out.Write(text)
+}
+
+func (textRenderer) DoubleEmphasis(out *bytes.Buffer, text []byte) {
+	out.Write(text)
+}
+
+func (textRenderer) Emphasis(out *bytes.Buffer, text []byte) {
+	out.Write(text)
+}
+
+func (textRenderer) Image(out *bytes.Buffer, _, _, alt []byte) {
+	out.Write(alt)
+}
+
+func (textRenderer) LineBreak(out *bytes.Buffer) {
+	out.WriteByte('\n')
+}
+
+func (textRenderer) Link(out *bytes.Buffer, _, _, content []byte) {
+	out.Write(content)
+}
+
+func (textRenderer) RawHtmlTag(*bytes.Buffer, []byte) {}
+
+func (textRenderer) TripleEmphasis(out *bytes.Buffer, text []byte) {
+	out.Write(text)
+}
+
+func (textRenderer) StrikeThrough(out *bytes.Buffer, text []byte) {
+	out.Write(text)
+}
+
+func (textRenderer) FootnoteRef(*bytes.Buffer, []byte, int) {}
+
+func (textRenderer) Entity(out *bytes.Buffer, entity []byte) {
+	out.WriteString(html.UnescapeString(string(entity)))
+}
+
+func (textRenderer) NormalText(out *bytes.Buffer, text []byte) {
+	out.Write(text)
+}
+
+func (textRenderer) DocumentHeader(*bytes.Buffer) {}
+func (textRenderer) DocumentFooter(*bytes.Buffer) {}
+
+func (textRenderer) GetFlags() int { return 0 }
+
+// ToText renders markdown as plain text, e.g. for reading an answer aloud.
+func ToText(content string) string {
+	text := blackfriday.Markdown([]byte(content), textRenderer{}, markdownExtensions)
+	return strings.TrimSpace(blankLinesRe.ReplaceAllString(string(text), "\n\n"))
+}
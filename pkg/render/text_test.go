@@ -0,0 +1,55 @@
+package render
+
+import "testing"
+
+func TestToText(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{
+			name:    "plain text",
+			content: "Привет, мир",
+			want:    "Привет, мир",
+		},
+		{
+			name:    "emphasis and code span",
+			content: "This is **bold**, *italic* and `code`.",
+			want:    "This is bold, italic and code.",
+		},
+		{
+			name:    "header and paragraphs",
+			content: "# Title\n\nFirst paragraph.\n\nSecond paragraph.",
+			want:    "Title\n\nFirst paragraph.\n\nSecond paragraph.",
+		},
+		{
+			name:    "link keeps its text",
+			content: "See [the docs](https://example.com) for details.",
+			want:    "See the docs for details.",
+		},
+		{
+			name:    "list",
+			content: "- one\n- two",
+			want:    "one\ntwo",
+		},
+		{
+			name:    "code block",
+			content: "```go\nfmt.Println(\"hi\")\n```",
+			want:    "fmt.Println(\"hi\")",
+		},
+		{
+			name:    "entity",
+			content: "Tom &amp; Jerry",
+			want:    "Tom & Jerry",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ToText(tt.content); got != tt.want {
+				t.Errorf("ToText(%q) = %q, want %q", tt.content, got, tt.want)
+			}
+		})
+	}
+}
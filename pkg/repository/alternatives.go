@@ -0,0 +1,43 @@
+package repository
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/dskvich/chatgpt-telegram-bot/pkg/domain"
+)
+
+// alternativesRepository keeps the answers of every chat and topic that wait to be picked.
+type alternativesRepository struct {
+	mu           sync.Mutex
+	alternatives map[string]domain.Alternatives
+}
+
+func NewAlternativesRepository() *alternativesRepository {
+	return &alternativesRepository{
+		alternatives: make(map[string]domain.Alternatives),
+	}
+}
+
+func (a *alternativesRepository) key(chatID int64, topicID int) string {
+	return fmt.Sprintf("%d:%d", chatID, topicID)
+}
+
+func (a *alternativesRepository) Save(alternatives domain.Alternatives) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	key := a.key(alternatives.Chat.ID, alternatives.Chat.TopicID)
+	a.alternatives[key] = alternatives
+}
+
+// Take returns the pending alternatives and forgets them, so every set is picked only once.
+func (a *alternativesRepository) Take(chatID int64, topicID int) (domain.Alternatives, bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	key := a.key(chatID, topicID)
+	alternatives, ok := a.alternatives[key]
+	delete(a.alternatives, key)
+	return alternatives, ok
+}
@@ -0,0 +1,79 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/dskvich/chatgpt-telegram-bot/pkg/domain"
+)
+
+type budgetsRepository struct {
+	db *sql.DB
+}
+
+func NewBudgetsRepository(db *sql.DB) *budgetsRepository {
+	return &budgetsRepository{db: db}
+}
+
+func (r *budgetsRepository) Save(ctx context.Context, budget domain.Budget) error {
+	const query = `
+		INSERT INTO budgets (scope, subject_id, period, limit_usd)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (scope, subject_id, period)
+		DO UPDATE SET
+			limit_usd = EXCLUDED.limit_usd
+	`
+
+	_, err := r.db.ExecContext(ctx, query, budget.Scope, budget.SubjectID, budget.Period, budget.Limit)
+	if err != nil {
+		return fmt.Errorf("saving budget: %w", err)
+	}
+
+	return nil
+}
+
+func (r *budgetsRepository) Delete(ctx context.Context, scope domain.BudgetScope, subjectID int64, period domain.BudgetPeriod) error {
+	const query = `
+		DELETE FROM budgets
+		WHERE scope = $1
+		  AND subject_id = $2
+		  AND period = $3
+	`
+
+	if _, err := r.db.ExecContext(ctx, query, scope, subjectID, period); err != nil {
+		return fmt.Errorf("deleting budget: %w", err)
+	}
+
+	return nil
+}
+
+func (r *budgetsRepository) List(ctx context.Context, scope domain.BudgetScope, subjectID int64) ([]domain.Budget, error) {
+	const query = `
+		SELECT scope, subject_id, period, limit_usd
+		FROM budgets
+		WHERE scope = $1
+		  AND subject_id = $2
+		ORDER BY period
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, scope, subjectID)
+	if err != nil {
+		return nil, fmt.Errorf("fetching budgets: %w", err)
+	}
+	defer rows.Close()
+
+	var res []domain.Budget
+	for rows.Next() {
+		var b domain.Budget
+		if err := rows.Scan(&b.Scope, &b.SubjectID, &b.Period, &b.Limit); err != nil {
+			return nil, fmt.Errorf("scanning budget: %w", err)
+		}
+		res = append(res, b)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating budgets: %w", err)
+	}
+
+	return res, nil
+}
@@ -0,0 +1,59 @@
+package repository
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// imageTTL is how long the last image of a chat can be edited or refined.
+const imageTTL = time.Hour
+
+type imageEntry struct {
+	image   []byte
+	savedAt time.Time
+}
+
+// imageRepository keeps the last image sent to or generated in every chat and topic for imageTTL.
+// Expired images are dropped on every save, so chats that went quiet do not hold on to them.
+type imageRepository struct {
+	mu     sync.RWMutex
+	images map[string]imageEntry
+}
+
+func NewImageRepository() *imageRepository {
+	return &imageRepository{
+		images: make(map[string]imageEntry),
+	}
+}
+
+func (i *imageRepository) key(chatID int64, topicID int) string {
+	return fmt.Sprintf("%d:%d", chatID, topicID)
+}
+
+func (i *imageRepository) Save(chatID int64, topicID int, image []byte) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	now := time.Now()
+	for key, entry := range i.images {
+		if now.Sub(entry.savedAt) > imageTTL {
+			delete(i.images, key)
+		}
+	}
+
+	key := i.key(chatID, topicID)
+	i.images[key] = imageEntry{image: image, savedAt: now}
+}
+
+func (i *imageRepository) Get(chatID int64, topicID int) ([]byte, bool) {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+
+	key := i.key(chatID, topicID)
+	entry, ok := i.images[key]
+	if !ok || time.Since(entry.savedAt) > imageTTL {
+		return nil, false
+	}
+	return entry.image, true
+}
@@ -0,0 +1,144 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/dskvich/chatgpt-telegram-bot/pkg/domain"
+)
+
+type knowledgeBaseRepository struct {
+	db *sql.DB
+}
+
+func NewKnowledgeBaseRepository(db *sql.DB) *knowledgeBaseRepository {
+	return &knowledgeBaseRepository{db: db}
+}
+
+// SaveDocument stores the document with its chunks and returns the ID of the document.
+func (k *knowledgeBaseRepository) SaveDocument(ctx context.Context, doc domain.KnowledgeDocument, chunks []domain.KnowledgeChunk) (int64, error) {
+	const (
+		documentQuery = `
+			INSERT INTO kb_documents (chat_id, title)
+			VALUES ($1, $2)
+			RETURNING id
+		`
+		chunkQuery = `
+			INSERT INTO kb_chunks (document_id, chat_id, position, content, embedding)
+			VALUES ($1, $2, $3, $4, $5)
+		`
+	)
+
+	tx, err := k.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var id int64
+	if err := tx.QueryRowContext(ctx, documentQuery, doc.ChatID, doc.Title).Scan(&id); err != nil {
+		return 0, fmt.Errorf("saving knowledge document: %w", err)
+	}
+
+	for _, chunk := range chunks {
+		if _, err := tx.ExecContext(ctx, chunkQuery, id, doc.ChatID, chunk.Position, chunk.Content, encodeEmbedding(chunk.Embedding)); err != nil {
+			return 0, fmt.Errorf("saving knowledge chunk: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("committing transaction: %w", err)
+	}
+
+	return id, nil
+}
+
+// ListDocuments returns the documents of the chat, the oldest first.
+func (k *knowledgeBaseRepository) ListDocuments(ctx context.Context, chatID int64) ([]domain.KnowledgeDocument, error) {
+	const query = `
+		SELECT d.id, d.chat_id, d.title, COUNT(c.id), d.created_at
+		FROM kb_documents d
+		LEFT JOIN kb_chunks c ON c.document_id = d.id
+		WHERE d.chat_id = $1
+		GROUP BY d.id
+		ORDER BY d.id
+	`
+
+	rows, err := k.db.QueryContext(ctx, query, chatID)
+	if err != nil {
+		return nil, fmt.Errorf("fetching knowledge documents: %w", err)
+	}
+	defer rows.Close()
+
+	var res []domain.KnowledgeDocument
+	for rows.Next() {
+		var doc domain.KnowledgeDocument
+		if err := rows.Scan(&doc.ID, &doc.ChatID, &doc.Title, &doc.Chunks, &doc.CreatedAt); err != nil {
+			return nil, fmt.Errorf("scanning knowledge document: %w", err)
+		}
+		res = append(res, doc)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating knowledge documents: %w", err)
+	}
+
+	return res, nil
+}
+
+// DeleteDocument removes a document of the chat with its chunks, domain.ErrNotFound if the chat
+// has no such document.
+func (k *knowledgeBaseRepository) DeleteDocument(ctx context.Context, chatID, id int64) error {
+	const query = `
+		DELETE FROM kb_documents
+		WHERE chat_id = $1
+		  AND id = $2
+	`
+
+	res, err := k.db.ExecContext(ctx, query, chatID, id)
+	if err != nil {
+		return fmt.Errorf("deleting knowledge document: %w", err)
+	}
+
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return domain.ErrNotFound
+	}
+
+	return nil
+}
+
+// ListChunks returns every chunk of the knowledge base of the chat.
+func (k *knowledgeBaseRepository) ListChunks(ctx context.Context, chatID int64) ([]domain.KnowledgeChunk, error) {
+	const query = `
+		SELECT c.id, c.document_id, d.title, c.position, c.content, c.embedding
+		FROM kb_chunks c
+		JOIN kb_documents d ON d.id = c.document_id
+		WHERE c.chat_id = $1
+	`
+
+	rows, err := k.db.QueryContext(ctx, query, chatID)
+	if err != nil {
+		return nil, fmt.Errorf("fetching knowledge chunks: %w", err)
+	}
+	defer rows.Close()
+
+	var res []domain.KnowledgeChunk
+	for rows.Next() {
+		var (
+			chunk     domain.KnowledgeChunk
+			embedding []byte
+		)
+		if err := rows.Scan(&chunk.ID, &chunk.DocumentID, &chunk.DocumentTitle, &chunk.Position, &chunk.Content, &embedding); err != nil {
+			return nil, fmt.Errorf("scanning knowledge chunk: %w", err)
+		}
+		chunk.Embedding = decodeEmbedding(embedding)
+		res = append(res, chunk)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating knowledge chunks: %w", err)
+	}
+
+	return res, nil
+}
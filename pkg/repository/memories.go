@@ -0,0 +1,138 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/binary"
+	"fmt"
+	"math"
+
+	"github.com/dskvich/chatgpt-telegram-bot/pkg/domain"
+)
+
+type memoriesRepository struct {
+	db *sql.DB
+}
+
+func NewMemoriesRepository(db *sql.DB) *memoriesRepository {
+	return &memoriesRepository{db: db}
+}
+
+func (m *memoriesRepository) Save(ctx context.Context, memory domain.Memory) error {
+	const query = `
+		INSERT INTO memories (user_id, content, embedding)
+		VALUES ($1, $2, $3)
+	`
+
+	if _, err := m.db.ExecContext(ctx, query, memory.UserID, memory.Content, encodeEmbedding(memory.Embedding)); err != nil {
+		return fmt.Errorf("saving memory: %w", err)
+	}
+
+	return nil
+}
+
+// ListByUser returns the memories of the user, the newest first.
+func (m *memoriesRepository) ListByUser(ctx context.Context, userID int64) ([]domain.Memory, error) {
+	const query = `
+		SELECT id, user_id, content, embedding, created_at
+		FROM memories
+		WHERE user_id = $1
+		ORDER BY created_at DESC, id DESC
+	`
+
+	rows, err := m.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("fetching memories: %w", err)
+	}
+	defer rows.Close()
+
+	var res []domain.Memory
+	for rows.Next() {
+		var (
+			memory    domain.Memory
+			embedding []byte
+		)
+		if err := rows.Scan(&memory.ID, &memory.UserID, &memory.Content, &embedding, &memory.CreatedAt); err != nil {
+			return nil, fmt.Errorf("scanning memory: %w", err)
+		}
+		memory.Embedding = decodeEmbedding(embedding)
+		res = append(res, memory)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating memories: %w", err)
+	}
+
+	return res, nil
+}
+
+// Delete removes a memory of the user, domain.ErrNotFound if the user has no such memory.
+func (m *memoriesRepository) Delete(ctx context.Context, userID, id int64) error {
+	const query = `
+		DELETE FROM memories
+		WHERE user_id = $1
+		  AND id = $2
+	`
+
+	res, err := m.db.ExecContext(ctx, query, userID, id)
+	if err != nil {
+		return fmt.Errorf("deleting memory: %w", err)
+	}
+
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return domain.ErrNotFound
+	}
+
+	return nil
+}
+
+func (m *memoriesRepository) DeleteAll(ctx context.Context, userID int64) error {
+	const query = `
+		DELETE FROM memories
+		WHERE user_id = $1
+	`
+
+	if _, err := m.db.ExecContext(ctx, query, userID); err != nil {
+		return fmt.Errorf("deleting memories: %w", err)
+	}
+
+	return nil
+}
+
+// Prune keeps only the newest memories of the user.
+func (m *memoriesRepository) Prune(ctx context.Context, userID int64, keep int) error {
+	const query = `
+		DELETE FROM memories
+		WHERE user_id = $1
+		  AND id NOT IN (
+			SELECT id
+			FROM memories
+			WHERE user_id = $1
+			ORDER BY created_at DESC, id DESC
+			LIMIT $2
+		  )
+	`
+
+	if _, err := m.db.ExecContext(ctx, query, userID, keep); err != nil {
+		return fmt.Errorf("pruning memories: %w", err)
+	}
+
+	return nil
+}
+
+// Embeddings are stored as little-endian float32 values.
+func encodeEmbedding(embedding []float32) []byte {
+	buf := make([]byte, 4*len(embedding))
+	for i, v := range embedding {
+		binary.LittleEndian.PutUint32(buf[4*i:], math.Float32bits(v))
+	}
+	return buf
+}
+
+func decodeEmbedding(buf []byte) []float32 {
+	embedding := make([]float32, len(buf)/4)
+	for i := range embedding {
+		embedding[i] = math.Float32frombits(binary.LittleEndian.Uint32(buf[4*i:]))
+	}
+	return embedding
+}
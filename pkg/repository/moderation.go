@@ -0,0 +1,76 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/dskvich/chatgpt-telegram-bot/pkg/domain"
+)
+
+const moderationCategoriesSeparator = ","
+
+type moderationRepository struct {
+	db *sql.DB
+}
+
+func NewModerationRepository(db *sql.DB) *moderationRepository {
+	return &moderationRepository{db: db}
+}
+
+// SaveCategories sets the categories blocked in the chat.
+func (m *moderationRepository) SaveCategories(ctx context.Context, chatID int64, categories []string) error {
+	const query = `
+		INSERT INTO moderation_categories (chat_id, categories)
+		VALUES ($1, $2)
+		ON CONFLICT (chat_id)
+		DO UPDATE SET
+			categories = EXCLUDED.categories
+	`
+
+	_, err := m.db.ExecContext(ctx, query, chatID, strings.Join(categories, moderationCategoriesSeparator))
+	if err != nil {
+		return fmt.Errorf("saving moderation categories: %w", err)
+	}
+
+	return nil
+}
+
+func (m *moderationRepository) DeleteCategories(ctx context.Context, chatID int64) error {
+	const query = `
+		DELETE FROM moderation_categories
+		WHERE chat_id = $1
+	`
+
+	if _, err := m.db.ExecContext(ctx, query, chatID); err != nil {
+		return fmt.Errorf("deleting moderation categories: %w", err)
+	}
+
+	return nil
+}
+
+// GetCategories returns the categories blocked in the chat, domain.ErrNotFound if the chat
+// uses the default of blocking every flagged category.
+func (m *moderationRepository) GetCategories(ctx context.Context, chatID int64) ([]string, error) {
+	const query = `
+		SELECT categories
+		FROM moderation_categories
+		WHERE chat_id = $1
+	`
+
+	var categories string
+	if err := m.db.QueryRowContext(ctx, query, chatID).Scan(&categories); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, domain.ErrNotFound
+		}
+		return nil, fmt.Errorf("fetching moderation categories by chatID: %w", err)
+	}
+
+	if categories == "" {
+		return nil, nil
+	}
+
+	return strings.Split(categories, moderationCategoriesSeparator), nil
+}
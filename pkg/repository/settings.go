@@ -19,18 +19,41 @@ func NewSettingsRepository(db *sql.DB) *settingsRepository {
 
 func (s *settingsRepository) Save(ctx context.Context, settings domain.Settings) error {
 	const query = `
-		INSERT INTO settings (chat_id, topic_id, text_model, system_prompt, image_model, ttl)
-		VALUES ($1, $2, $3, $4, $5, $6)
+		INSERT INTO settings (chat_id, topic_id, text_model, system_prompt, image_model, image_size, image_quality, ttl,
+			max_history_depth, voice_reply, voice, reasoning_effort,
+			temperature, top_p, presence_penalty, frequency_penalty, max_tokens, seed, alternatives,
+			voice_translation, translate_to)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21)
 		ON CONFLICT (chat_id, topic_id)
 		DO UPDATE SET
 			text_model = EXCLUDED.text_model,
 		    system_prompt = EXCLUDED.system_prompt,
 			image_model = EXCLUDED.image_model,
-			ttl = EXCLUDED.ttl
+			image_size = EXCLUDED.image_size,
+			image_quality = EXCLUDED.image_quality,
+			ttl = EXCLUDED.ttl,
+			max_history_depth = EXCLUDED.max_history_depth,
+			voice_reply = EXCLUDED.voice_reply,
+			voice = EXCLUDED.voice,
+			reasoning_effort = EXCLUDED.reasoning_effort,
+			temperature = EXCLUDED.temperature,
+			top_p = EXCLUDED.top_p,
+			presence_penalty = EXCLUDED.presence_penalty,
+			frequency_penalty = EXCLUDED.frequency_penalty,
+			max_tokens = EXCLUDED.max_tokens,
+			seed = EXCLUDED.seed,
+			alternatives = EXCLUDED.alternatives,
+			voice_translation = EXCLUDED.voice_translation,
+			translate_to = EXCLUDED.translate_to
 	`
 
 	_, err := s.db.ExecContext(ctx, query,
-		settings.ChatID, settings.TopicID, settings.TextModel, settings.SystemPrompt, settings.ImageModel, settings.TTL)
+		settings.ChatID, settings.TopicID, settings.TextModel, settings.SystemPrompt, settings.ImageModel,
+		settings.ImageSize, settings.ImageQuality, settings.TTL, settings.MaxHistoryDepth,
+		settings.VoiceReply, settings.Voice, settings.ReasoningEffort,
+		settings.Params.Temperature, settings.Params.TopP, settings.Params.PresencePenalty, settings.Params.FrequencyPenalty,
+		settings.Params.MaxTokens, settings.Params.Seed, settings.Alternatives,
+		settings.VoiceTranslation, settings.TranslateTo)
 	if err != nil {
 		return fmt.Errorf("saving settings: %w", err)
 	}
@@ -40,7 +63,10 @@ func (s *settingsRepository) Save(ctx context.Context, settings domain.Settings)
 
 func (s *settingsRepository) Get(ctx context.Context, chatID int64, topicID int) (*domain.Settings, error) {
 	const query = `
-		SELECT chat_id, topic_id, text_model, system_prompt, image_model, ttl
+		SELECT chat_id, topic_id, text_model, system_prompt, image_model, image_size, image_quality, ttl,
+			max_history_depth, voice_reply, voice, reasoning_effort,
+			temperature, top_p, presence_penalty, frequency_penalty, max_tokens, seed, alternatives,
+			voice_translation, translate_to
 		FROM settings
 		WHERE chat_id = $1
 		  AND topic_id = $2
@@ -48,7 +74,12 @@ func (s *settingsRepository) Get(ctx context.Context, chatID int64, topicID int)
 
 	var res domain.Settings
 	err := s.db.QueryRowContext(ctx, query, chatID, topicID).
-		Scan(&res.ChatID, &res.TopicID, &res.TextModel, &res.SystemPrompt, &res.ImageModel, &res.TTL)
+		Scan(&res.ChatID, &res.TopicID, &res.TextModel, &res.SystemPrompt, &res.ImageModel,
+			&res.ImageSize, &res.ImageQuality, &res.TTL, &res.MaxHistoryDepth,
+			&res.VoiceReply, &res.Voice, &res.ReasoningEffort,
+			&res.Params.Temperature, &res.Params.TopP, &res.Params.PresencePenalty, &res.Params.FrequencyPenalty,
+			&res.Params.MaxTokens, &res.Params.Seed, &res.Alternatives,
+			&res.VoiceTranslation, &res.TranslateTo)
 
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
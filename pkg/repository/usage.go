@@ -0,0 +1,92 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/dskvich/chatgpt-telegram-bot/pkg/domain"
+)
+
+type usageRepository struct {
+	db *sql.DB
+}
+
+func NewUsageRepository(db *sql.DB) *usageRepository {
+	return &usageRepository{db: db}
+}
+
+func (u *usageRepository) Save(ctx context.Context, record domain.UsageRecord) error {
+	const query = `
+		INSERT INTO usage_records (user_id, chat_id, topic_id, model,
+			prompt_tokens, completion_tokens, images, transcriptions, audio_seconds, speech_characters, cost)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+	`
+
+	_, err := u.db.ExecContext(ctx, query,
+		record.UserID, record.ChatID, record.TopicID, record.Model,
+		record.Usage.PromptTokens, record.Usage.CompletionTokens, record.Usage.Images, record.Usage.Transcriptions,
+		record.Usage.AudioSeconds, record.Usage.SpeechCharacters, record.Usage.Cost)
+	if err != nil {
+		return fmt.Errorf("saving usage: %w", err)
+	}
+
+	return nil
+}
+
+func (u *usageRepository) TotalByUser(ctx context.Context, userID int64, since time.Time) (domain.Usage, error) {
+	const query = `
+		SELECT COALESCE(SUM(prompt_tokens), 0),
+		       COALESCE(SUM(completion_tokens), 0),
+		       COALESCE(SUM(images), 0),
+		       COALESCE(SUM(transcriptions), 0),
+		       COALESCE(SUM(audio_seconds), 0),
+		       COALESCE(SUM(speech_characters), 0),
+		       COALESCE(SUM(cost), 0)
+		FROM usage_records
+		WHERE user_id = $1
+		  AND created_at >= $2
+	`
+
+	res, err := u.total(ctx, query, userID, since)
+	if err != nil {
+		return domain.Usage{}, fmt.Errorf("fetching usage by userID: %w", err)
+	}
+
+	return res, nil
+}
+
+func (u *usageRepository) TotalByChat(ctx context.Context, chatID int64, since time.Time) (domain.Usage, error) {
+	const query = `
+		SELECT COALESCE(SUM(prompt_tokens), 0),
+		       COALESCE(SUM(completion_tokens), 0),
+		       COALESCE(SUM(images), 0),
+		       COALESCE(SUM(transcriptions), 0),
+		       COALESCE(SUM(audio_seconds), 0),
+		       COALESCE(SUM(speech_characters), 0),
+		       COALESCE(SUM(cost), 0)
+		FROM usage_records
+		WHERE chat_id = $1
+		  AND created_at >= $2
+	`
+
+	res, err := u.total(ctx, query, chatID, since)
+	if err != nil {
+		return domain.Usage{}, fmt.Errorf("fetching usage by chatID: %w", err)
+	}
+
+	return res, nil
+}
+
+func (u *usageRepository) total(ctx context.Context, query string, id int64, since time.Time) (domain.Usage, error) {
+	var res domain.Usage
+	err := u.db.QueryRowContext(ctx, query, id, since).
+		Scan(&res.PromptTokens, &res.CompletionTokens, &res.Images, &res.Transcriptions, &res.AudioSeconds,
+			&res.SpeechCharacters, &res.Cost)
+	if err != nil {
+		return domain.Usage{}, err
+	}
+
+	return res, nil
+}
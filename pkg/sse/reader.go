@@ -0,0 +1,77 @@
+package sse
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+const (
+	bufferSize  = 64 * 1024
+	maxLineSize = 1024 * 1024
+)
+
+// Event is a single server-sent event. Name is empty for unnamed events.
+type Event struct {
+	Name string
+	Data string
+}
+
+// Read parses a text/event-stream body and calls fn for every complete event until the body
+// ends or fn returns an error. io.EOF returned by fn stops reading without an error.
+func Read(r io.Reader, fn func(Event) error) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, bufferSize), maxLineSize)
+
+	var (
+		event Event
+		data  []string
+	)
+
+	dispatch := func() error {
+		if len(data) == 0 {
+			event = Event{}
+			return nil
+		}
+		event.Data = strings.Join(data, "\n")
+		err := fn(event)
+		event, data = Event{}, nil
+		return err
+	}
+
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		switch {
+		case line == "":
+			if err := dispatch(); err != nil {
+				return ignoreEOF(err)
+			}
+		case strings.HasPrefix(line, ":"):
+			// comment or keep-alive
+		default:
+			field, value, _ := strings.Cut(line, ":")
+			value = strings.TrimPrefix(value, " ")
+			switch field {
+			case "event":
+				event.Name = value
+			case "data":
+				data = append(data, value)
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("reading event stream: %w", err)
+	}
+
+	return ignoreEOF(dispatch())
+}
+
+func ignoreEOF(err error) error {
+	if errors.Is(err, io.EOF) {
+		return nil
+	}
+	return err
+}
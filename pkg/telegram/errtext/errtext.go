@@ -0,0 +1,37 @@
+package errtext
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+
+	"github.com/dskvich/chatgpt-telegram-bot/pkg/domain"
+	"github.com/dskvich/chatgpt-telegram-bot/pkg/logger"
+)
+
+var hints = []struct {
+	err  error
+	text string
+}{
+	{domain.ErrRateLimited, "⏳ слишком много запросов. Подождите минуту и попробуйте снова."},
+	{domain.ErrInsufficientQuota, "💳 закончилась квота API. Сообщите администратору бота."},
+	{domain.ErrContextLengthExceeded, "📚 диалог слишком длинный для модели. Начните новый чат командой /new или уменьшите /history."},
+	{domain.ErrContentPolicy, "🚫 запрос отклонён политикой безопасности. Переформулируйте его."},
+	{domain.ErrInvalidModel, "🤖 выбранная модель недоступна. Выберите другую через /text_models или /image_models."},
+	{domain.ErrUnauthorized, "🔑 ошибка авторизации в API. Сообщите администратору бота."},
+	{context.DeadlineExceeded, "⌛ ответ не пришёл вовремя. Попробуйте ещё раз."},
+}
+
+// Format logs the failure of an AI request and returns a message for the user. The user gets
+// advice on what to do next, the raw API response only goes to the logs.
+func Format(ctx context.Context, action string, err error) string {
+	slog.ErrorContext(ctx, action, logger.Err(err))
+
+	for _, h := range hints {
+		if errors.Is(err, h.err) {
+			return "❌ " + action + ": " + h.text
+		}
+	}
+
+	return "❌ " + action + ": что-то пошло не так. Попробуйте позже."
+}
@@ -0,0 +1,61 @@
+package files
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/go-telegram/bot"
+)
+
+// downloader fetches files sent to the bot through its own HTTP client, so downloads share the
+// timeouts and the proxy of the Telegram traffic and stop when the context is done.
+type downloader struct {
+	hc *http.Client
+}
+
+func NewDownloader(hc *http.Client) *downloader {
+	return &downloader{hc: hc}
+}
+
+// Open starts downloading the file and returns its content as a stream. The caller closes it.
+func (d *downloader) Open(ctx context.Context, b *bot.Bot, fileID string) (io.ReadCloser, error) {
+	file, err := b.GetFile(ctx, &bot.GetFileParams{FileID: fileID})
+	if err != nil {
+		return nil, fmt.Errorf("getting file metadata: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, b.FileDownloadLink(file), nil)
+	if err != nil {
+		return nil, fmt.Errorf("creating download request: %w", err)
+	}
+
+	resp, err := d.hc.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("downloading file: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("downloading file: unexpected status %s", resp.Status)
+	}
+
+	return resp.Body, nil
+}
+
+// Download fetches the whole content of the file.
+func (d *downloader) Download(ctx context.Context, b *bot.Bot, fileID string) ([]byte, error) {
+	body, err := d.Open(ctx, b, fileID)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return nil, fmt.Errorf("reading file: %w", err)
+	}
+
+	return data, nil
+}
@@ -3,22 +3,29 @@ package handlers
 import (
 	"context"
 	"log/slog"
+	"time"
 
+	"github.com/dskvich/chatgpt-telegram-bot/pkg/domain"
 	"github.com/go-telegram/bot"
 	"github.com/go-telegram/bot/models"
 )
 
 type ChatClearer interface {
+	Get(chatID int64, topicID int) (domain.Chat, time.Time, bool)
 	Clear(chatID int64, topicID int)
 }
 
-func ClearChat(clearer ChatClearer) bot.HandlerFunc {
+func ClearChat(clearer ChatClearer, memoryKeeper MemoryKeeper) bot.HandlerFunc {
 	return func(ctx context.Context, b *bot.Bot, update *models.Update) {
 		slog.InfoContext(ctx, "Clearing chat")
 
 		chatID := update.Message.Chat.ID
 		topicID := update.Message.MessageThreadID
 
+		if chat, _, ok := clearer.Get(chatID, topicID); ok {
+			rememberChat(ctx, memoryKeeper, update.Message, chat)
+		}
+
 		clearer.Clear(chatID, topicID)
 
 		b.SendMessage(ctx, &bot.SendMessageParams{
@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math"
+	"slices"
+	"strings"
+
+	"github.com/dskvich/chatgpt-telegram-bot/pkg/domain"
+	"github.com/dskvich/chatgpt-telegram-bot/pkg/llm"
+	"github.com/dskvich/chatgpt-telegram-bot/pkg/logger"
+	"github.com/go-telegram/bot/models"
+)
+
+const (
+	// maxDocumentFileSize is the largest file the Bot API lets bots download.
+	maxDocumentFileSize = 20 << 20
+	// A document may take half of the context window, but no more than maxDocumentTokens.
+	maxDocumentTokens   = 50_000
+	documentChunkTokens = 800
+	maxDocumentChunks   = 1000
+
+	defaultDocumentQuestion = "Кратко перескажи содержание документа."
+)
+
+type documentExtractor interface {
+	ExtractText(ctx context.Context, fileName, mimeType string, data []byte) (string, error)
+}
+
+type embedder interface {
+	CreateEmbeddings(ctx context.Context, inputs []string) ([][]float32, domain.Usage, error)
+}
+
+// documentPrompt puts the document in front of the question. A document that does not fit into
+// its share of the model context window is cut into chunks and only the chunks most similar to
+// the question are kept, in their original order. Without embeddings the first chunks are kept.
+func documentPrompt(
+	ctx context.Context,
+	embedder embedder,
+	model string,
+	doc *models.Document,
+	text, question string,
+) (string, domain.Usage) {
+	budget := min(llm.ContextWindow(model)/2, maxDocumentTokens)
+
+	if llm.EstimateTextTokens(text) <= budget {
+		return fmt.Sprintf("Документ «%s»:\n<<<\n%s\n>>>\n\n%s", doc.FileName, text, question), domain.Usage{}
+	}
+
+	chunks := llm.SplitText(text, documentChunkTokens)
+	chunks = chunks[:min(len(chunks), maxDocumentChunks)]
+
+	order, usage, err := rankChunks(ctx, embedder, chunks, question)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to rank document chunks, keeping the beginning", logger.Err(err))
+		order = make([]int, len(chunks))
+		for i := range order {
+			order[i] = i
+		}
+	}
+
+	var selected []int
+	used := 0
+	for _, i := range order {
+		tokens := llm.EstimateTextTokens(chunks[i])
+		if used+tokens > budget {
+			continue
+		}
+		used += tokens
+		selected = append(selected, i)
+	}
+	slices.Sort(selected)
+
+	slog.InfoContext(ctx, "Document does not fit into the context, selected chunks",
+		"chunks", len(chunks), "selected", len(selected))
+
+	parts := make([]string, 0, len(selected))
+	for _, i := range selected {
+		parts = append(parts, chunks[i])
+	}
+
+	return fmt.Sprintf("Фрагменты документа «%s», относящиеся к вопросу:\n<<<\n%s\n>>>\n\n%s",
+		doc.FileName, strings.Join(parts, "\n…\n"), question), usage
+}
+
+// rankChunks returns the chunk indexes ordered by similarity to the question, the most similar first.
+func rankChunks(ctx context.Context, embedder embedder, chunks []string, question string) ([]int, domain.Usage, error) {
+	embeddings, usage, err := embedder.CreateEmbeddings(ctx, append([]string{question}, chunks...))
+	if err != nil {
+		return nil, usage, err
+	}
+
+	return llm.RankBySimilarity(embeddings[0], embeddings[1:], math.Inf(-1), 0), usage, nil
+}
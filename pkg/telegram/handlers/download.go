@@ -0,0 +1,11 @@
+package handlers
+
+import (
+	"context"
+
+	"github.com/go-telegram/bot"
+)
+
+type fileDownloader interface {
+	Download(ctx context.Context, b *bot.Bot, fileID string) ([]byte, error)
+}
@@ -0,0 +1,145 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/dskvich/chatgpt-telegram-bot/pkg/domain"
+	"github.com/dskvich/chatgpt-telegram-bot/pkg/logger"
+	"github.com/dskvich/chatgpt-telegram-bot/pkg/telegram/errtext"
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"github.com/samber/lo"
+)
+
+type editImageSettingsProvider interface {
+	Get(ctx context.Context, chatID int64, topicID int) (*domain.Settings, error)
+}
+
+type editImageStateClearer interface {
+	Clear(chatID int64, topicID int)
+}
+
+type editImageStore interface {
+	Get(chatID int64, topicID int) ([]byte, bool)
+	Save(chatID int64, topicID int, image []byte)
+}
+
+type editImageEditor interface {
+	EditImage(ctx context.Context, source []byte, prompt string, settings domain.ImageSettings) ([]byte, error)
+	CreateImageVariation(ctx context.Context, source []byte, settings domain.ImageSettings) ([]byte, error)
+}
+
+type editImageUsageSaver interface {
+	Save(ctx context.Context, record domain.UsageRecord) error
+}
+
+// EditImage changes a photo sent with the prompt, the photo the message replies to, or the last
+// image of the chat. A bare "нарисуй" without a description creates a variation instead.
+func EditImage(
+	settingsProvider editImageSettingsProvider,
+	stateClearer editImageStateClearer,
+	imageStore editImageStore,
+	imageEditor editImageEditor,
+	downloader fileDownloader,
+	usageSaver editImageUsageSaver,
+) bot.HandlerFunc {
+	sourcePhotoID := func(msg *models.Message) string {
+		if len(msg.Photo) > 0 {
+			return msg.Photo[len(msg.Photo)-1].FileID
+		}
+		if msg.ReplyToMessage != nil && len(msg.ReplyToMessage.Photo) > 0 {
+			return msg.ReplyToMessage.Photo[len(msg.ReplyToMessage.Photo)-1].FileID
+		}
+		return ""
+	}
+
+	return func(ctx context.Context, b *bot.Bot, update *models.Update) {
+		chatID := update.Message.Chat.ID
+		topicID := update.Message.MessageThreadID
+		prompt := lo.CoalesceOrEmpty(update.Message.Text, update.Message.Caption)
+
+		stateClearer.Clear(chatID, topicID)
+
+		var source []byte
+		if fileID := sourcePhotoID(update.Message); fileID != "" {
+			data, err := downloader.Download(ctx, b, fileID)
+			if err != nil {
+				b.SendMessage(ctx, &bot.SendMessageParams{
+					ChatID:          chatID,
+					MessageThreadID: topicID,
+					Text:            fmt.Sprintf("❌ Не удалось получить фото файл: %s", err),
+				})
+				return
+			}
+			source = data
+		} else if data, ok := imageStore.Get(chatID, topicID); ok {
+			source = data
+		} else {
+			b.SendMessage(ctx, &bot.SendMessageParams{
+				ChatID:          chatID,
+				MessageThreadID: topicID,
+				Text:            "❌ Нет изображения для изменения. Отправьте фото или попросите нарисовать картинку.",
+			})
+			return
+		}
+
+		settings, err := settingsProvider.Get(ctx, chatID, topicID)
+		if err != nil && !errors.Is(err, domain.ErrNotFound) {
+			b.SendMessage(ctx, &bot.SendMessageParams{
+				ChatID:          chatID,
+				MessageThreadID: topicID,
+				Text:            fmt.Sprintf("❌ Не удалось получить настройки: %s", err),
+			})
+			return
+		}
+
+		settings, _ = lo.Coalesce(settings, &domain.Settings{})
+		imageSettings := domain.EditImageSettings(settings.ImageSettings())
+
+		var imageData []byte
+		if isVariation := len(strings.Fields(prompt)) == 1 && domain.IsImagePrompt(prompt); isVariation {
+			slog.InfoContext(ctx, "Creating image variation", "size", imageSettings.Size)
+			imageData, err = imageEditor.CreateImageVariation(ctx, source, imageSettings)
+		} else {
+			slog.InfoContext(ctx, "Editing image", "size", imageSettings.Size, "prompt", prompt)
+			imageData, err = imageEditor.EditImage(ctx, source, prompt, imageSettings)
+		}
+		if err != nil {
+			b.SendMessage(ctx, &bot.SendMessageParams{
+				ChatID:          chatID,
+				MessageThreadID: topicID,
+				Text:            errtext.Format(ctx, "Не удалось изменить изображение", err),
+			})
+			return
+		}
+
+		if err := usageSaver.Save(ctx, domain.UsageRecord{
+			UserID:    update.Message.From.ID,
+			ChatID:    chatID,
+			TopicID:   topicID,
+			Model:     string(imageSettings.Model),
+			ImageSize: imageSettings.Size,
+			Usage:     domain.Usage{Images: 1},
+		}); err != nil {
+			slog.ErrorContext(ctx, "Failed to save usage", logger.Err(err))
+		}
+
+		imageStore.Save(chatID, topicID, imageData)
+
+		b.SendPhoto(ctx, &bot.SendPhotoParams{
+			ChatID:          chatID,
+			MessageThreadID: topicID,
+			Photo: &models.InputFileUpload{
+				Data: bytes.NewReader(imageData),
+			},
+			ReplyMarkup: &models.InlineKeyboardMarkup{
+				InlineKeyboard: [][]models.InlineKeyboardButton{{refineImageButton}},
+			},
+		})
+	}
+}
@@ -6,17 +6,17 @@ import (
 	"encoding/base64"
 	"errors"
 	"fmt"
-	"io"
 	"log/slog"
-	"net/http"
-	"net/url"
 	"strconv"
 	"strings"
 	"time"
 	"unicode/utf8"
 
 	"github.com/dskvich/chatgpt-telegram-bot/pkg/domain"
+	"github.com/dskvich/chatgpt-telegram-bot/pkg/llm"
+	"github.com/dskvich/chatgpt-telegram-bot/pkg/logger"
 	"github.com/dskvich/chatgpt-telegram-bot/pkg/render"
+	"github.com/dskvich/chatgpt-telegram-bot/pkg/telegram/errtext"
 	"github.com/go-telegram/bot"
 	"github.com/go-telegram/bot/models"
 	"github.com/samber/lo"
@@ -31,22 +31,44 @@ type generateContentChatProvider interface {
 	Save(chat domain.Chat)
 }
 
-type generateContentAIService interface {
-	GenerateImage(ctx context.Context, prompt string) ([]byte, error)
-	CreateChatCompletion(ctx context.Context, chat *domain.Chat) (*domain.Message, error)
+type generateContentImageGenerator interface {
+	GenerateImage(ctx context.Context, prompt string, settings domain.ImageSettings) ([]byte, error)
+}
+
+type generateContentChatCompleter interface {
+	CreateChatCompletion(ctx context.Context, chat *domain.Chat) (*domain.Completion, error)
+	StreamChatCompletion(ctx context.Context, chat *domain.Chat, onDelta func(delta string)) (*domain.Completion, error)
 }
 
 type generateContentPromptSaver interface {
 	Save(ctx context.Context, prompt string) (int64, error)
 }
 
+type generateContentImageSaver interface {
+	Save(chatID int64, topicID int, image []byte)
+}
+
+type generateContentUsageSaver interface {
+	Save(ctx context.Context, record domain.UsageRecord) error
+}
+
 func GenerateContent(
 	settingsProvider generateContentSettingsProvider,
 	chatProvider generateContentChatProvider,
 	promptSaver generateContentPromptSaver,
-	aiService generateContentAIService,
+	imageGenerator generateContentImageGenerator,
+	chatCompleter generateContentChatCompleter,
+	imageSaver generateContentImageSaver,
+	alternativesStore alternativesStore,
+	memoryKeeper MemoryKeeper,
+	documentExtractor documentExtractor,
+	embedder embedder,
+	knowledgeBase knowledgeSearcher,
+	speechSynthesizer speechSynthesizer,
+	speechConverter speechConverter,
+	downloader fileDownloader,
+	usageSaver generateContentUsageSaver,
 ) bot.HandlerFunc {
-	const maxTelegramMessageLength = 4096
 	const moreButtonText = "Еще"
 
 	isExpired := func(lastUpdate time.Time, ttl time.Duration) bool {
@@ -56,32 +78,14 @@ func GenerateContent(
 		return time.Since(lastUpdate) > ttl
 	}
 
-	findCutIndex := func(text string, maxLength int) int {
-		if i := strings.LastIndex(text[:maxLength], "<pre>"); i > -1 {
-			return i
-		}
-		if i := strings.LastIndex(text[:maxLength], "\n"); i > -1 {
-			return i
-		}
-		return maxLength
-	}
+	saveUsage := func(ctx context.Context, update *models.Update, record domain.UsageRecord) {
+		record.UserID = update.Message.From.ID
+		record.ChatID = update.Message.Chat.ID
+		record.TopicID = update.Message.MessageThreadID
 
-	downloadFileToBuffer := func(link string) ([]byte, error) {
-		resp, err := http.Get(link)
-		if err != nil {
-			return nil, err
+		if err := usageSaver.Save(ctx, record); err != nil {
+			slog.ErrorContext(ctx, "Failed to save usage", logger.Err(err))
 		}
-		defer resp.Body.Close()
-
-		if resp.StatusCode != http.StatusOK {
-			return nil, err
-		}
-
-		data, err := io.ReadAll(resp.Body)
-		if err != nil {
-			return nil, err
-		}
-		return data, nil
 	}
 
 	shortDuration := func(d time.Duration) string {
@@ -96,10 +100,21 @@ func GenerateContent(
 		topicID := update.Message.MessageThreadID
 		prompt := lo.CoalesceOrEmpty(update.Message.Text, update.Message.Caption)
 
-		isImagePrompt := strings.Contains(strings.ToLower(prompt), "рисуй") ||
-			strings.Contains(strings.ToLower(prompt), "draw")
+		settings, err := settingsProvider.Get(ctx, chatID, topicID)
+		if err != nil && !errors.Is(err, domain.ErrNotFound) {
+			b.SendMessage(ctx, &bot.SendMessageParams{
+				ChatID:          update.Message.Chat.ID,
+				MessageThreadID: update.Message.MessageThreadID,
+				Text:            fmt.Sprintf("❌ Не удалось получить настройки: %s", err),
+			})
+			return
+		}
+
+		settings, _ = lo.Coalesce(settings, &domain.Settings{})
+		settings.TextModel, _ = lo.Coalesce(settings.TextModel, domain.Gpt4oMiniModel)
+		settings.TTL, _ = lo.Coalesce(settings.TTL, 15*time.Minute)
 
-		if isImagePrompt {
+		if domain.IsImagePrompt(prompt) && update.Message.Document == nil && settings.TranslateTo == "" {
 			promptID, err := promptSaver.Save(ctx, prompt)
 			if err != nil {
 				b.SendMessage(ctx, &bot.SendMessageParams{
@@ -110,19 +125,33 @@ func GenerateContent(
 				return
 			}
 
-			imageData, err := aiService.GenerateImage(ctx, prompt)
+			imageSettings := settings.ImageSettings()
+
+			imageData, err := imageGenerator.GenerateImage(ctx, prompt, imageSettings)
 			if err != nil {
 				b.SendMessage(ctx, &bot.SendMessageParams{
 					ChatID:          chatID,
 					MessageThreadID: topicID,
-					Text:            fmt.Sprintf("❌ Не удалось сгенерировать изображение: %s", err),
+					Text:            errtext.Format(ctx, "Не удалось сгенерировать изображение", err),
 				})
 				return
 			}
 
+			saveUsage(ctx, update, domain.UsageRecord{
+				Model:        string(imageSettings.Model),
+				ImageSize:    imageSettings.Size,
+				ImageQuality: imageSettings.Quality,
+				Usage:        domain.Usage{Images: 1},
+			})
+
+			imageSaver.Save(chatID, topicID, imageData)
+
 			kb := &models.InlineKeyboardMarkup{
 				InlineKeyboard: [][]models.InlineKeyboardButton{
-					{{Text: moreButtonText, CallbackData: domain.GenImageCallbackPrefix + strconv.FormatInt(promptID, 10)}},
+					{
+						{Text: moreButtonText, CallbackData: domain.GenImageCallbackPrefix + strconv.FormatInt(promptID, 10)},
+						refineImageButton,
+					},
 				},
 			}
 
@@ -140,55 +169,110 @@ func GenerateContent(
 		// in case user send photo
 		var imageBytes []byte
 		if len(update.Message.Photo) > 0 {
-			imageFile, err := b.GetFile(ctx, &bot.GetFileParams{
-				FileID: update.Message.Photo[len(update.Message.Photo)-1].FileID,
-			})
+			imageBytes, err = downloader.Download(ctx, b, update.Message.Photo[len(update.Message.Photo)-1].FileID)
 			if err != nil {
 				b.SendMessage(ctx, &bot.SendMessageParams{
 					ChatID:          update.Message.Chat.ID,
 					MessageThreadID: update.Message.MessageThreadID,
-					Text:            fmt.Sprintf("❌ Не удалось получить метадату фото файла: %s", err),
+					Text:            fmt.Sprintf("❌ Не удалось получить фото файл: %s", err),
+				})
+				return
+			}
+
+			imageSaver.Save(chatID, topicID, imageBytes)
+		}
+
+		// in case user send document
+		var documentText string
+		if doc := update.Message.Document; doc != nil {
+			if doc.FileSize > maxDocumentFileSize {
+				b.SendMessage(ctx, &bot.SendMessageParams{
+					ChatID:          chatID,
+					MessageThreadID: topicID,
+					Text:            "❌ Файл слишком большой: боты могут скачивать файлы до 20 МБ.",
 				})
 				return
 			}
 
-			imageFileURL, err := url.Parse(b.FileDownloadLink(imageFile))
+			data, err := downloader.Download(ctx, b, doc.FileID)
 			if err != nil {
 				b.SendMessage(ctx, &bot.SendMessageParams{
-					ChatID:          update.Message.Chat.ID,
-					MessageThreadID: update.Message.MessageThreadID,
-					Text:            fmt.Sprintf("❌ Не удалось получить ссылку на фото файл: %s", err),
+					ChatID:          chatID,
+					MessageThreadID: topicID,
+					Text:            fmt.Sprintf("❌ Не удалось получить файл: %s", err),
 				})
 				return
 			}
 
-			imageBytes, err = downloadFileToBuffer(imageFileURL.String())
+			documentText, err = documentExtractor.ExtractText(ctx, doc.FileName, doc.MimeType, data)
+			if errors.Is(err, domain.ErrUnsupportedDocument) {
+				b.SendMessage(ctx, &bot.SendMessageParams{
+					ChatID:          chatID,
+					MessageThreadID: topicID,
+					Text:            "📄 Этот тип файлов не поддерживается. Отправьте текст (.txt, .md), исходный код, .pdf или .docx.",
+				})
+				return
+			}
 			if err != nil {
 				b.SendMessage(ctx, &bot.SendMessageParams{
-					ChatID:          update.Message.Chat.ID,
-					MessageThreadID: update.Message.MessageThreadID,
-					Text:            fmt.Sprintf("❌ Не удалось получить фото файл: %s", err),
+					ChatID:          chatID,
+					MessageThreadID: topicID,
+					Text:            fmt.Sprintf("❌ Не удалось прочитать файл: %s", err),
+				})
+				return
+			}
+
+			if strings.TrimSpace(documentText) == "" {
+				b.SendMessage(ctx, &bot.SendMessageParams{
+					ChatID:          chatID,
+					MessageThreadID: topicID,
+					Text:            "📄 В файле не найден текст. Сканы без текстового слоя не поддерживаются.",
 				})
 				return
 			}
 		}
 
-		settings, err := settingsProvider.Get(ctx, chatID, topicID)
-		if err != nil && !errors.Is(err, domain.ErrNotFound) {
-			b.SendMessage(ctx, &bot.SendMessageParams{
-				ChatID:          update.Message.Chat.ID,
-				MessageThreadID: update.Message.MessageThreadID,
-				Text:            fmt.Sprintf("❌ Не удалось получить настройки: %s", err),
+		// In translation mode the message is translated instead of answered and the chat is left untouched.
+		if settings.TranslateTo != "" {
+			text := lo.CoalesceOrEmpty(documentText, prompt)
+			if llm.EstimateTextTokens(text) > llm.ReservedOutputTokens {
+				b.SendMessage(ctx, &bot.SendMessageParams{
+					ChatID:          chatID,
+					MessageThreadID: topicID,
+					Text:            "❌ Текст слишком длинный для перевода одним сообщением. Разбейте его на части.",
+				})
+				return
+			}
+
+			request := translationChat(settings, chatID, topicID, userContent(text, imageBytes))
+
+			slog.InfoContext(ctx, "Calling AI for translation", "model", request.Model, "language", settings.TranslateTo)
+
+			stream := newMessageStream(b, update.Message.Chat, topicID, speakKeyboard)
+			if err := stream.Start(ctx); err != nil {
+				slog.ErrorContext(ctx, "Failed to start message stream", logger.Err(err))
+				return
+			}
+
+			completion, err := chatCompleter.StreamChatCompletion(ctx, &request, func(delta string) {
+				stream.Write(ctx, delta)
 			})
+			if err != nil {
+				stream.Abort(ctx, errtext.Format(ctx, "Не удалось перевести сообщение", err))
+				return
+			}
+
+			saveUsage(ctx, update, domain.UsageRecord{Model: request.Model, Usage: completion.Usage})
+			stream.Close(ctx)
 			return
 		}
 
-		settings, _ = lo.Coalesce(settings, &domain.Settings{})
-		settings.TextModel, _ = lo.Coalesce(settings.TextModel, domain.Gpt4oMiniModel)
-		settings.TTL, _ = lo.Coalesce(settings.TTL, 15*time.Minute)
-
 		chat, lastUpdate, ok := chatProvider.Get(chatID, topicID)
 		if !ok || isExpired(lastUpdate, settings.TTL) {
+			if ok {
+				rememberChat(ctx, memoryKeeper, update.Message, chat)
+			}
+
 			slog.DebugContext(ctx, "Creating a new chat with parameters",
 				"textModel", settings.TextModel,
 				"ttl", settings.TTL,
@@ -214,94 +298,154 @@ func GenerateContent(
 				Text:            text,
 				ParseMode:       models.ParseModeHTML,
 			})
-		}
 
-		// Add user message
-		content := []domain.ContentPart{{Type: domain.ContentPartTypeText, Data: prompt}}
+			recallMemories(ctx, memoryKeeper, update.Message, &chat, prompt)
+		}
 
-		if len(imageBytes) > 0 {
-			imageContent := domain.ContentPart{
-				Type: domain.ContentPartTypeImage,
-				Data: "data:image/jpeg;base64," + base64.StdEncoding.EncodeToString(imageBytes),
-			}
+		chat.ReasoningEffort = settings.ReasoningEffort
+		chat.Params = settings.Params
+		// The limit may have been set for another model.
+		if limit := llm.MaxOutputTokens(chat.Model); chat.Params.MaxTokens > limit {
+			chat.Params.MaxTokens = limit
+		}
 
-			if prompt != "" {
-				content = []domain.ContentPart{
-					{Type: domain.ContentPartTypeText, Data: prompt},
-					imageContent,
-				}
-			} else {
-				content = []domain.ContentPart{imageContent}
+		if documentText != "" {
+			var usage domain.Usage
+			question := lo.CoalesceOrEmpty(prompt, defaultDocumentQuestion)
+			prompt, usage = documentPrompt(ctx, embedder, chat.Model, update.Message.Document, documentText, question)
+			if usage.PromptTokens > 0 {
+				saveUsage(ctx, update, domain.UsageRecord{Model: domain.TextEmbedding3SmallModel, Usage: usage})
 			}
 		}
 
+		// Add user message
 		chat.Messages = append(chat.Messages, domain.Message{
 			Role:         domain.MessageRoleUser,
-			ContentParts: content,
+			ContentParts: userContent(prompt, imageBytes),
 		})
 
-		slog.InfoContext(ctx, "Calling AI for chat completion", "model", chat.Model, "messagesCount", len(chat.Messages))
-
-		respMessage, err := aiService.CreateChatCompletion(ctx, &chat)
+		// The request carries the knowledge base chunks for this question only and the history trimmed
+		// to the context window; the chat keeps the whole history.
+		request := chat
+		question := lo.CoalesceOrEmpty(update.Message.Text, update.Message.Caption)
+		sources, usage, err := knowledgeBase.Search(ctx, chatID, question)
 		if err != nil {
-			b.SendMessage(ctx, &bot.SendMessageParams{
-				ChatID:          chatID,
-				MessageThreadID: topicID,
-				Text:            fmt.Sprintf("❌ Не удалось сгенерировать ответ: %s", err),
+			slog.ErrorContext(ctx, "Failed to search the knowledge base", logger.Err(err))
+		}
+		if usage.PromptTokens > 0 {
+			saveUsage(ctx, update, domain.UsageRecord{Model: domain.TextEmbedding3SmallModel, Usage: usage})
+		}
+		if len(sources) > 0 {
+			request.SystemPrompt = strings.TrimSpace(request.SystemPrompt + "\n\n" + knowledgePrompt(sources))
+			slog.InfoContext(ctx, "Added knowledge base chunks to the request", "chunks", len(sources))
+		}
+
+		messagesCount := len(request.Messages)
+		llm.FitContext(&request, settings.MaxHistoryDepth, max(llm.OutputTokens(request.Model), request.Params.MaxTokens))
+		if trimmed := messagesCount - len(request.Messages); trimmed > 0 {
+			slog.InfoContext(ctx, "Trimmed chat history to fit the context window", "trimmedMessages", trimmed)
+		}
+
+		slog.InfoContext(ctx, "Calling AI for chat completion", "model", chat.Model, "messagesCount", len(request.Messages))
+
+		if settings.Alternatives > 1 {
+			request.Choices = settings.Alternatives
+
+			completion, err := chatCompleter.CreateChatCompletion(ctx, &request)
+			if err != nil {
+				b.SendMessage(ctx, &bot.SendMessageParams{
+					ChatID:          chatID,
+					MessageThreadID: topicID,
+					Text:            errtext.Format(ctx, "Не удалось сгенерировать ответ", err),
+				})
+				return
+			}
+
+			saveUsage(ctx, update, domain.UsageRecord{Model: chat.Model, Usage: completion.Usage})
+
+			// Providers without multiple choices return a single answer, which is offered as the only variant.
+			choices := lo.Ternary(len(completion.Alternatives) > 1, completion.Alternatives, []domain.Message{completion.Message})
+
+			messageIDs, err := sendAlternatives(ctx, b, chatID, topicID, choices, sources)
+			if err != nil {
+				slog.ErrorContext(ctx, "Failed to send alternatives", logger.Err(err))
+				return
+			}
+
+			alternativesStore.Save(domain.Alternatives{
+				Chat:          chat,
+				ChatUpdatedAt: lastUpdate,
+				Choices:       choices,
+				MessageIDs:    messageIDs,
 			})
 			return
 		}
 
-		if respMessage == nil || len(respMessage.ContentParts) == 0 {
-			b.SendMessage(ctx, &bot.SendMessageParams{
-				ChatID:          chatID,
-				MessageThreadID: topicID,
-				Text:            "❌ Ответ пустой или отсутствует.",
-			})
+		stream := newMessageStream(b, update.Message.Chat, topicID, speakKeyboard)
+		if err := stream.Start(ctx); err != nil {
+			slog.ErrorContext(ctx, "Failed to start message stream", logger.Err(err))
 			return
 		}
 
-		chat.Messages = append(chat.Messages, *respMessage)
-		chatProvider.Save(chat)
+		completion, err := chatCompleter.StreamChatCompletion(ctx, &request, func(delta string) {
+			stream.Write(ctx, delta)
+		})
+		if err != nil {
+			stream.Abort(ctx, errtext.Format(ctx, "Не удалось сгенерировать ответ", err))
+			return
+		}
 
-		part := respMessage.ContentParts[0] // Assume only one part for now
-		if part.Type != domain.ContentPartTypeText {
-			b.SendMessage(ctx, &bot.SendMessageParams{
-				ChatID:          chatID,
-				MessageThreadID: topicID,
-				Text:            fmt.Sprintf("❌ Неожиданный тип ответа: %+v", part),
-			})
+		saveUsage(ctx, update, domain.UsageRecord{Model: chat.Model, Usage: completion.Usage})
+
+		if len(completion.Message.ContentParts) == 0 {
+			stream.Abort(ctx, "❌ Ответ пустой или отсутствует.")
 			return
 		}
 
-		htmlText := render.ToHTML(part.Data)
-		for htmlText != "" {
-			if utf8.RuneCountInString(htmlText) <= maxTelegramMessageLength {
-				b.SendMessage(ctx, &bot.SendMessageParams{
-					ChatID:          chatID,
-					MessageThreadID: topicID,
-					Text:            htmlText,
-					ParseMode:       models.ParseModeHTML,
-				})
+		chat.Messages = append(chat.Messages, completion.Steps...)
+		chat.Messages = append(chat.Messages, completion.Message)
+		chatProvider.Save(chat)
+
+		if footer := citedSources(completion.Message.ContentParts[0].Data, sources); footer != "" {
+			stream.Write(ctx, "\n\n"+footer)
+		}
+		stream.Close(ctx)
+
+		if settings.VoiceReply {
+			// The markup would be read out, only the text is spoken and billed.
+			answer := render.ToText(completion.Message.ContentParts[0].Data)
+			if err := sendSpeech(ctx, b, speechSynthesizer, speechConverter, chatID, topicID, answer, settings.Voice); err != nil {
+				slog.ErrorContext(ctx, "Failed to send voice reply", logger.Err(err))
 				return
 			}
 
-			cutIndex := findCutIndex(htmlText, maxTelegramMessageLength)
-			_, err := b.SendMessage(ctx, &bot.SendMessageParams{
-				ChatID:          chatID,
-				MessageThreadID: topicID,
-				Text:            htmlText[:cutIndex],
-				ParseMode:       models.ParseModeHTML,
+			saveUsage(ctx, update, domain.UsageRecord{
+				Model: domain.TTS1Model,
+				Usage: domain.Usage{SpeechCharacters: utf8.RuneCountInString(answer)},
 			})
-			if err != nil {
-				b.SendMessage(ctx, &bot.SendMessageParams{
-					ChatID:          chatID,
-					MessageThreadID: topicID,
-					Text:            fmt.Sprintf("❌ Не удалось сгенерировать ответ: %s", err),
-				})
+		}
+	}
+}
+
+// userContent builds the content of a user message from the text and an optional photo.
+func userContent(text string, imageBytes []byte) []domain.ContentPart {
+	content := []domain.ContentPart{{Type: domain.ContentPartTypeText, Data: text}}
+
+	if len(imageBytes) > 0 {
+		imageContent := domain.ContentPart{
+			Type: domain.ContentPartTypeImage,
+			Data: "data:image/jpeg;base64," + base64.StdEncoding.EncodeToString(imageBytes),
+		}
+
+		if text != "" {
+			content = []domain.ContentPart{
+				{Type: domain.ContentPartTypeText, Data: text},
+				imageContent,
 			}
-			htmlText = htmlText[cutIndex:]
-			time.Sleep(time.Second) // Basic rate limit management
+		} else {
+			content = []domain.ContentPart{imageContent}
 		}
 	}
+
+	return content
 }
@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/dskvich/chatgpt-telegram-bot/pkg/domain"
+)
+
+type knowledgeSearcher interface {
+	Search(ctx context.Context, chatID int64, question string) ([]domain.KnowledgeChunk, domain.Usage, error)
+}
+
+var citationRe = regexp.MustCompile(`\[(\d+)]`)
+
+// knowledgePrompt numbers the chunks, so the model can cite them as [1], [2]...
+func knowledgePrompt(chunks []domain.KnowledgeChunk) string {
+	var sb strings.Builder
+	sb.WriteString("Ниже фрагменты базы знаний чата. Если они относятся к вопросу, отвечай по ним и ссылайся на них " +
+		"номерами в квадратных скобках, например [1]. Если ответа в них нет, скажи об этом.")
+
+	for i, c := range chunks {
+		fmt.Fprintf(&sb, "\n\n[%d] «%s», фрагмент %d:\n%s", i+1, c.DocumentTitle, c.Position+1, c.Content)
+	}
+
+	return sb.String()
+}
+
+// citedSources lists the chunks the answer refers to, in the order of their numbers.
+func citedSources(answer string, chunks []domain.KnowledgeChunk) string {
+	var cited []int
+	for _, m := range citationRe.FindAllStringSubmatch(answer, -1) {
+		n, err := strconv.Atoi(m[1])
+		if err != nil || n < 1 || n > len(chunks) || slices.Contains(cited, n) {
+			continue
+		}
+		cited = append(cited, n)
+	}
+	if len(cited) == 0 {
+		return ""
+	}
+	slices.Sort(cited)
+
+	var sb strings.Builder
+	sb.WriteString("📚 Источники:")
+	for _, n := range cited {
+		c := chunks[n-1]
+		fmt.Fprintf(&sb, "\n[%d] «%s» (#%d), фрагмент %d", n, c.DocumentTitle, c.DocumentID, c.Position+1)
+	}
+
+	return sb.String()
+}
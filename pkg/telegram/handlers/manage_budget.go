@@ -0,0 +1,185 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dskvich/chatgpt-telegram-bot/pkg/domain"
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+)
+
+type ManageBudgetProvider interface {
+	Save(ctx context.Context, budget domain.Budget) error
+	Delete(ctx context.Context, scope domain.BudgetScope, subjectID int64, period domain.BudgetPeriod) error
+	List(ctx context.Context, scope domain.BudgetScope, subjectID int64) ([]domain.Budget, error)
+}
+
+type ManageBudgetSpendingProvider interface {
+	TotalByUser(ctx context.Context, userID int64, since time.Time) (domain.Usage, error)
+	TotalByChat(ctx context.Context, chatID int64, since time.Time) (domain.Usage, error)
+}
+
+// ManageBudget shows the budgets of the current user and chat on a bare /budget. Admins change them with
+// "/budget <user|chat> <id|me|this> <day|month> <usd|off>".
+func ManageBudget(provider ManageBudgetProvider, spending ManageBudgetSpendingProvider, adminIDs []int64) bot.HandlerFunc {
+	const usageText = "Использование: /budget <user|chat> <id|me|this> <day|month> <сумма в $|off>"
+
+	periodNames := map[domain.BudgetPeriod]string{
+		domain.BudgetPeriodDay:   "день",
+		domain.BudgetPeriodMonth: "месяц",
+	}
+
+	parseBudget := func(args []string, update *models.Update) (domain.Budget, bool, error) {
+		const argsCount = 4
+		if len(args) != argsCount {
+			return domain.Budget{}, false, errors.New(usageText)
+		}
+
+		budget := domain.Budget{
+			Scope:  domain.BudgetScope(args[0]),
+			Period: domain.BudgetPeriod(args[2]),
+		}
+
+		switch {
+		case budget.Scope == domain.BudgetScopeUser && args[1] == "me":
+			budget.SubjectID = update.Message.From.ID
+		case budget.Scope == domain.BudgetScopeChat && args[1] == "this":
+			budget.SubjectID = update.Message.Chat.ID
+		case budget.Scope == domain.BudgetScopeUser || budget.Scope == domain.BudgetScopeChat:
+			id, err := strconv.ParseInt(args[1], 10, 64)
+			if err != nil {
+				return domain.Budget{}, false, fmt.Errorf("invalid id: %s", args[1])
+			}
+			budget.SubjectID = id
+		default:
+			return domain.Budget{}, false, fmt.Errorf("unsupported scope: %s", args[0])
+		}
+
+		if _, ok := periodNames[budget.Period]; !ok {
+			return domain.Budget{}, false, fmt.Errorf("unsupported period: %s", args[2])
+		}
+
+		if args[3] == "off" {
+			return budget, true, nil
+		}
+
+		limit, err := strconv.ParseFloat(strings.TrimPrefix(args[3], "$"), 64)
+		if err != nil || limit <= 0 {
+			return domain.Budget{}, false, fmt.Errorf("invalid amount: %s", args[3])
+		}
+		budget.Limit = limit
+
+		return budget, false, nil
+	}
+
+	describe := func(ctx context.Context, title string, scope domain.BudgetScope, subjectID int64) (string, error) {
+		budgets, err := provider.List(ctx, scope, subjectID)
+		if err != nil {
+			return "", err
+		}
+		if len(budgets) == 0 {
+			return fmt.Sprintf("%s: без ограничений\n", title), nil
+		}
+
+		var sb strings.Builder
+		fmt.Fprintf(&sb, "%s:\n", title)
+		for _, budget := range budgets {
+			since := budget.Period.Start(time.Now())
+
+			var usage domain.Usage
+			if scope == domain.BudgetScopeChat {
+				usage, err = spending.TotalByChat(ctx, subjectID, since)
+			} else {
+				usage, err = spending.TotalByUser(ctx, subjectID, since)
+			}
+			if err != nil {
+				return "", err
+			}
+
+			fmt.Fprintf(&sb, "• %s: $%.2f из $%.2f\n", periodNames[budget.Period], usage.Cost, budget.Limit)
+		}
+
+		return sb.String(), nil
+	}
+
+	return func(ctx context.Context, b *bot.Bot, update *models.Update) {
+		chatID := update.Message.Chat.ID
+		topicID := update.Message.MessageThreadID
+
+		args := strings.Fields(update.Message.Text)[1:]
+
+		if len(args) == 0 {
+			userText, err := describe(ctx, "👤 Пользователь", domain.BudgetScopeUser, update.Message.From.ID)
+			if err == nil {
+				var chatText string
+				chatText, err = describe(ctx, "💬 Чат", domain.BudgetScopeChat, chatID)
+				userText += chatText
+			}
+			if err != nil {
+				b.SendMessage(ctx, &bot.SendMessageParams{
+					ChatID:          chatID,
+					MessageThreadID: topicID,
+					Text:            fmt.Sprintf("❌ Не удалось получить бюджет: %s", err),
+				})
+				return
+			}
+
+			b.SendMessage(ctx, &bot.SendMessageParams{
+				ChatID:          chatID,
+				MessageThreadID: topicID,
+				Text:            "💰 Бюджеты:\n" + userText,
+			})
+			return
+		}
+
+		if !slices.Contains(adminIDs, update.Message.From.ID) {
+			b.SendMessage(ctx, &bot.SendMessageParams{
+				ChatID:          chatID,
+				MessageThreadID: topicID,
+				Text:            "❌ Изменять бюджеты могут только администраторы",
+			})
+			return
+		}
+
+		budget, remove, err := parseBudget(args, update)
+		if err != nil {
+			b.SendMessage(ctx, &bot.SendMessageParams{
+				ChatID:          chatID,
+				MessageThreadID: topicID,
+				Text:            fmt.Sprintf("❌ Не удалось прочитать бюджет: %s", err),
+			})
+			return
+		}
+
+		if remove {
+			err = provider.Delete(ctx, budget.Scope, budget.SubjectID, budget.Period)
+		} else {
+			err = provider.Save(ctx, budget)
+		}
+		if err != nil {
+			b.SendMessage(ctx, &bot.SendMessageParams{
+				ChatID:          chatID,
+				MessageThreadID: topicID,
+				Text:            fmt.Sprintf("❌ Не удалось сохранить бюджет: %s", err),
+			})
+			return
+		}
+
+		text := fmt.Sprintf("✅ Бюджет %s %d на %s: $%.2f", budget.Scope, budget.SubjectID, periodNames[budget.Period], budget.Limit)
+		if remove {
+			text = fmt.Sprintf("✅ Бюджет %s %d на %s снят", budget.Scope, budget.SubjectID, periodNames[budget.Period])
+		}
+
+		b.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID:          chatID,
+			MessageThreadID: topicID,
+			Text:            text,
+		})
+	}
+}
@@ -0,0 +1,177 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/dskvich/chatgpt-telegram-bot/pkg/domain"
+	"github.com/dskvich/chatgpt-telegram-bot/pkg/logger"
+	"github.com/dskvich/chatgpt-telegram-bot/pkg/telegram/errtext"
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"github.com/samber/lo"
+)
+
+const maxKnowledgeTitleLength = 60
+
+type KnowledgeBase interface {
+	Add(ctx context.Context, chatID int64, title, text string) (*domain.KnowledgeDocument, domain.Usage, error)
+	List(ctx context.Context, chatID int64) ([]domain.KnowledgeDocument, error)
+	Remove(ctx context.Context, chatID, id int64) error
+}
+
+type manageKnowledgeBaseUsageSaver interface {
+	Save(ctx context.Context, record domain.UsageRecord) error
+}
+
+// ManageKnowledgeBase handles "/kb add [title]" sent as a file caption or in reply to a message or
+// a file, "/kb list" and "/kb remove <id>". The knowledge base is shared by all topics of the chat.
+func ManageKnowledgeBase(
+	kb KnowledgeBase,
+	extractor documentExtractor,
+	downloader fileDownloader,
+	usageSaver manageKnowledgeBaseUsageSaver,
+) bot.HandlerFunc {
+	const usageText = `Использование:
+/kb add [название] — в подписи к файлу или в ответ на сообщение или файл
+/kb list — документы базы знаний
+/kb remove <id> — удалить документ`
+
+	// readSource returns the title and the text of the message to add.
+	readSource := func(ctx context.Context, b *bot.Bot, msg *models.Message) (string, string, error) {
+		if doc := msg.Document; doc != nil {
+			data, err := downloader.Download(ctx, b, doc.FileID)
+			if err != nil {
+				return "", "", err
+			}
+
+			text, err := extractor.ExtractText(ctx, doc.FileName, doc.MimeType, data)
+			return doc.FileName, text, err
+		}
+
+		text := lo.CoalesceOrEmpty(msg.Text, msg.Caption)
+		title, _, _ := strings.Cut(strings.TrimSpace(text), "\n")
+		if utf8.RuneCountInString(title) > maxKnowledgeTitleLength {
+			title = string([]rune(title)[:maxKnowledgeTitleLength]) + "…"
+		}
+
+		return title, text, nil
+	}
+
+	return func(ctx context.Context, b *bot.Bot, update *models.Update) {
+		msg := update.Message
+		chatID := msg.Chat.ID
+		topicID := msg.MessageThreadID
+
+		reply := func(text string) {
+			b.SendMessage(ctx, &bot.SendMessageParams{
+				ChatID:          chatID,
+				MessageThreadID: topicID,
+				Text:            text,
+			})
+		}
+
+		args := strings.Fields(lo.CoalesceOrEmpty(msg.Text, msg.Caption))[1:]
+		if len(args) == 0 {
+			reply(usageText)
+			return
+		}
+
+		switch args[0] {
+		case "add":
+			source := msg
+			if msg.Document == nil {
+				source = msg.ReplyToMessage
+			}
+			if source == nil {
+				reply(usageText)
+				return
+			}
+
+			if source.Document != nil && source.Document.FileSize > maxDocumentFileSize {
+				reply("❌ Файл слишком большой: боты могут скачивать файлы до 20 МБ.")
+				return
+			}
+
+			title, text, err := readSource(ctx, b, source)
+			if errors.Is(err, domain.ErrUnsupportedDocument) {
+				reply("📄 Этот тип файлов не поддерживается. Отправьте текст (.txt, .md), исходный код, .pdf или .docx.")
+				return
+			}
+			if err != nil {
+				reply(errtext.Format(ctx, "Не удалось прочитать документ", err))
+				return
+			}
+			if strings.TrimSpace(text) == "" {
+				reply("📄 В документе не найден текст.")
+				return
+			}
+
+			title = lo.CoalesceOrEmpty(strings.Join(args[1:], " "), title)
+
+			doc, usage, err := kb.Add(ctx, chatID, title, text)
+			if usage.PromptTokens > 0 {
+				if err := usageSaver.Save(ctx, domain.UsageRecord{
+					UserID:  msg.From.ID,
+					ChatID:  chatID,
+					TopicID: topicID,
+					Model:   domain.TextEmbedding3SmallModel,
+					Usage:   usage,
+				}); err != nil {
+					slog.ErrorContext(ctx, "Failed to save usage", logger.Err(err))
+				}
+			}
+			if err != nil {
+				reply(errtext.Format(ctx, "Не удалось добавить документ в базу знаний", err))
+				return
+			}
+
+			reply(fmt.Sprintf("✅ Документ #%d «%s» добавлен в базу знаний, фрагментов: %d", doc.ID, doc.Title, doc.Chunks))
+		case "list":
+			docs, err := kb.List(ctx, chatID)
+			if err != nil {
+				reply(fmt.Sprintf("❌ Не удалось получить базу знаний: %s", err))
+				return
+			}
+			if len(docs) == 0 {
+				reply("📚 База знаний чата пуста. Добавьте документ командой /kb add.")
+				return
+			}
+
+			var sb strings.Builder
+			sb.WriteString("📚 База знаний чата:")
+			for _, doc := range docs {
+				fmt.Fprintf(&sb, "\n#%d «%s» — фрагментов: %d, добавлен %s", doc.ID, doc.Title, doc.Chunks, doc.CreatedAt.Format("02.01.2006"))
+			}
+			reply(sb.String())
+		case "remove":
+			if len(args) != 2 {
+				reply(usageText)
+				return
+			}
+
+			id, err := strconv.ParseInt(strings.TrimPrefix(args[1], "#"), 10, 64)
+			if err != nil {
+				reply(usageText)
+				return
+			}
+
+			if err := kb.Remove(ctx, chatID, id); errors.Is(err, domain.ErrNotFound) {
+				reply(fmt.Sprintf("❌ Документ #%d не найден в базе знаний чата", id))
+				return
+			} else if err != nil {
+				reply(fmt.Sprintf("❌ Не удалось удалить документ: %s", err))
+				return
+			}
+
+			reply(fmt.Sprintf("✅ Документ #%d удален из базы знаний", id))
+		default:
+			reply(usageText)
+		}
+	}
+}
@@ -0,0 +1,173 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/dskvich/chatgpt-telegram-bot/pkg/domain"
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"github.com/samber/lo"
+)
+
+const (
+	deleteAllMemories    = "all"
+	maxListedMemories    = 20
+	maxMemoryTextLength  = 200
+	memoryButtonsInARow  = 5
+	emptyMemoriesMessage = "🧠 Я пока ничего о вас не помню."
+)
+
+type ManageMemoriesProvider interface {
+	ListByUser(ctx context.Context, userID int64) ([]domain.Memory, error)
+	Delete(ctx context.Context, userID, id int64) error
+	DeleteAll(ctx context.Context, userID int64) error
+}
+
+// memoriesMessage lists the newest memories of the user with a delete button for each of them.
+func memoriesMessage(memories []domain.Memory) (string, *models.InlineKeyboardMarkup) {
+	if len(memories) == 0 {
+		return emptyMemoriesMessage, &models.InlineKeyboardMarkup{InlineKeyboard: [][]models.InlineKeyboardButton{}}
+	}
+
+	listed := memories[:min(len(memories), maxListedMemories)]
+
+	var sb strings.Builder
+	sb.WriteString("🧠 Что я о вас помню:\n")
+	for i, m := range listed {
+		content := m.Content
+		if utf8.RuneCountInString(content) > maxMemoryTextLength {
+			content = string([]rune(content)[:maxMemoryTextLength]) + "…"
+		}
+		fmt.Fprintf(&sb, "\n%d. %s", i+1, content)
+	}
+	if len(memories) > len(listed) {
+		fmt.Fprintf(&sb, "\n\n…и еще %d", len(memories)-len(listed))
+	}
+
+	buttons := lo.Map(listed, func(m domain.Memory, i int) models.InlineKeyboardButton {
+		return models.InlineKeyboardButton{
+			Text:         fmt.Sprintf("🗑 %d", i+1),
+			CallbackData: domain.DeleteMemoryCallbackPrefix + strconv.FormatInt(m.ID, 10),
+		}
+	})
+
+	rows := lo.Chunk(buttons, memoryButtonsInARow)
+	rows = append(rows, []models.InlineKeyboardButton{
+		{Text: "🗑 Забыть все", CallbackData: domain.DeleteMemoryCallbackPrefix + deleteAllMemories},
+	})
+
+	return sb.String(), &models.InlineKeyboardMarkup{InlineKeyboard: rows}
+}
+
+// ManageMemories lists what the bot remembers about the user; "/memories clear" forgets everything.
+func ManageMemories(provider ManageMemoriesProvider) bot.HandlerFunc {
+	return func(ctx context.Context, b *bot.Bot, update *models.Update) {
+		chatID := update.Message.Chat.ID
+		topicID := update.Message.MessageThreadID
+
+		userID, ok := memoryOwner(update.Message)
+		if !ok {
+			b.SendMessage(ctx, &bot.SendMessageParams{
+				ChatID:          chatID,
+				MessageThreadID: topicID,
+				Text:            "🔒 Воспоминания доступны только в личном чате с ботом.",
+			})
+			return
+		}
+
+		if args := strings.Fields(update.Message.Text)[1:]; len(args) == 1 && args[0] == "clear" {
+			if err := provider.DeleteAll(ctx, userID); err != nil {
+				b.SendMessage(ctx, &bot.SendMessageParams{
+					ChatID:          chatID,
+					MessageThreadID: topicID,
+					Text:            fmt.Sprintf("❌ Не удалось удалить воспоминания: %s", err),
+				})
+				return
+			}
+
+			b.SendMessage(ctx, &bot.SendMessageParams{
+				ChatID:          chatID,
+				MessageThreadID: topicID,
+				Text:            "✅ Все воспоминания удалены",
+			})
+			return
+		}
+
+		memories, err := provider.ListByUser(ctx, userID)
+		if err != nil {
+			b.SendMessage(ctx, &bot.SendMessageParams{
+				ChatID:          chatID,
+				MessageThreadID: topicID,
+				Text:            fmt.Sprintf("❌ Не удалось получить воспоминания: %s", err),
+			})
+			return
+		}
+
+		text, kb := memoriesMessage(memories)
+
+		b.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID:          chatID,
+			MessageThreadID: topicID,
+			Text:            text,
+			ReplyMarkup:     kb,
+		})
+	}
+}
+
+// DeleteMemory forgets the memory behind the pressed button and updates the list in place.
+// Only the memories of the user who pressed the button are affected.
+func DeleteMemory(provider ManageMemoriesProvider) bot.HandlerFunc {
+	return func(ctx context.Context, b *bot.Bot, update *models.Update) {
+		msg := update.CallbackQuery.Message.Message
+		chatID := msg.Chat.ID
+		topicID := msg.MessageThreadID
+		userID := update.CallbackQuery.From.ID
+
+		b.AnswerCallbackQuery(ctx, &bot.AnswerCallbackQueryParams{
+			CallbackQueryID: update.CallbackQuery.ID,
+			ShowAlert:       false,
+		})
+
+		var err error
+		if idRaw := strings.TrimPrefix(update.CallbackQuery.Data, domain.DeleteMemoryCallbackPrefix); idRaw == deleteAllMemories {
+			err = provider.DeleteAll(ctx, userID)
+		} else if id, parseErr := strconv.ParseInt(idRaw, 10, 64); parseErr != nil {
+			err = fmt.Errorf("invalid memory id: %s", idRaw)
+		} else {
+			err = provider.Delete(ctx, userID, id)
+		}
+
+		if err != nil && !errors.Is(err, domain.ErrNotFound) {
+			b.SendMessage(ctx, &bot.SendMessageParams{
+				ChatID:          chatID,
+				MessageThreadID: topicID,
+				Text:            fmt.Sprintf("❌ Не удалось удалить воспоминание: %s", err),
+			})
+			return
+		}
+
+		memories, err := provider.ListByUser(ctx, userID)
+		if err != nil {
+			b.SendMessage(ctx, &bot.SendMessageParams{
+				ChatID:          chatID,
+				MessageThreadID: topicID,
+				Text:            fmt.Sprintf("❌ Не удалось получить воспоминания: %s", err),
+			})
+			return
+		}
+
+		text, kb := memoriesMessage(memories)
+
+		b.EditMessageText(ctx, &bot.EditMessageTextParams{
+			ChatID:      chatID,
+			MessageID:   msg.ID,
+			Text:        text,
+			ReplyMarkup: kb,
+		})
+	}
+}
@@ -0,0 +1,119 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/dskvich/chatgpt-telegram-bot/pkg/domain"
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+)
+
+type ManageModerationProvider interface {
+	GetCategories(ctx context.Context, chatID int64) ([]string, error)
+	SaveCategories(ctx context.Context, chatID int64, categories []string) error
+	DeleteCategories(ctx context.Context, chatID int64) error
+}
+
+// ManageModeration shows the moderation settings of the chat on a bare /moderation. Admins choose
+// the blocked categories with "/moderation <category...>" or go back to all of them with "/moderation all".
+func ManageModeration(provider ManageModerationProvider, policy domain.ModerationPolicy, adminIDs []int64) bot.HandlerFunc {
+	const allCategories = "all"
+
+	policyNames := map[domain.ModerationPolicy]string{
+		domain.ModerationPolicyOff:   "выключена",
+		domain.ModerationPolicyWarn:  "предупреждать",
+		domain.ModerationPolicyBlock: "блокировать",
+	}
+
+	usageText := "Использование: /moderation <категория...>|all\nКатегории: " + strings.Join(domain.ModerationCategories, ", ")
+
+	return func(ctx context.Context, b *bot.Bot, update *models.Update) {
+		chatID := update.Message.Chat.ID
+		topicID := update.Message.MessageThreadID
+
+		args := strings.Fields(update.Message.Text)[1:]
+
+		if len(args) == 0 {
+			categories, err := provider.GetCategories(ctx, chatID)
+			if err != nil && !errors.Is(err, domain.ErrNotFound) {
+				b.SendMessage(ctx, &bot.SendMessageParams{
+					ChatID:          chatID,
+					MessageThreadID: topicID,
+					Text:            fmt.Sprintf("❌ Не удалось получить настройки модерации: %s", err),
+				})
+				return
+			}
+
+			categoriesText := strings.Join(categories, ", ")
+			if errors.Is(err, domain.ErrNotFound) {
+				categoriesText = "все"
+			} else if len(categories) == 0 {
+				categoriesText = "нет"
+			}
+
+			b.SendMessage(ctx, &bot.SendMessageParams{
+				ChatID:          chatID,
+				MessageThreadID: topicID,
+				Text:            fmt.Sprintf("🛡️ Модерация: %s\nБлокируемые категории: %s", policyNames[policy], categoriesText),
+			})
+			return
+		}
+
+		if !slices.Contains(adminIDs, update.Message.From.ID) {
+			b.SendMessage(ctx, &bot.SendMessageParams{
+				ChatID:          chatID,
+				MessageThreadID: topicID,
+				Text:            "❌ Изменять настройки модерации могут только администраторы",
+			})
+			return
+		}
+
+		if len(args) == 1 && args[0] == allCategories {
+			if err := provider.DeleteCategories(ctx, chatID); err != nil {
+				b.SendMessage(ctx, &bot.SendMessageParams{
+					ChatID:          chatID,
+					MessageThreadID: topicID,
+					Text:            fmt.Sprintf("❌ Не удалось сохранить настройки модерации: %s", err),
+				})
+				return
+			}
+
+			b.SendMessage(ctx, &bot.SendMessageParams{
+				ChatID:          chatID,
+				MessageThreadID: topicID,
+				Text:            "✅ Блокируются все категории",
+			})
+			return
+		}
+
+		for _, category := range args {
+			if !slices.Contains(domain.ModerationCategories, category) {
+				b.SendMessage(ctx, &bot.SendMessageParams{
+					ChatID:          chatID,
+					MessageThreadID: topicID,
+					Text:            fmt.Sprintf("❌ Неизвестная категория: %s\n%s", category, usageText),
+				})
+				return
+			}
+		}
+
+		if err := provider.SaveCategories(ctx, chatID, args); err != nil {
+			b.SendMessage(ctx, &bot.SendMessageParams{
+				ChatID:          chatID,
+				MessageThreadID: topicID,
+				Text:            fmt.Sprintf("❌ Не удалось сохранить настройки модерации: %s", err),
+			})
+			return
+		}
+
+		b.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID:          chatID,
+			MessageThreadID: topicID,
+			Text:            "✅ Блокируемые категории: " + strings.Join(args, ", "),
+		})
+	}
+}
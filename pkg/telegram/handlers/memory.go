@@ -0,0 +1,74 @@
+package handlers
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/dskvich/chatgpt-telegram-bot/pkg/domain"
+	"github.com/dskvich/chatgpt-telegram-bot/pkg/logger"
+	"github.com/go-telegram/bot/models"
+)
+
+// MemoryKeeper remembers facts about users across chats. A nil keeper turns long-term memory off.
+type MemoryKeeper interface {
+	Remember(ctx context.Context, userID int64, chat domain.Chat) error
+	Recall(ctx context.Context, userID int64, chat domain.Chat, question string) ([]domain.Memory, error)
+}
+
+// rememberTimeout bounds extracting facts, which outlives the update it was started by.
+const rememberTimeout = 2 * time.Minute
+
+// memoryOwner returns the user whose memories the message may use. Memory only works in private chats:
+// a group chat has several authors and its system prompt is seen by everyone in it.
+func memoryOwner(msg *models.Message) (int64, bool) {
+	if msg.Chat.Type != models.ChatTypePrivate || msg.From == nil {
+		return 0, false
+	}
+	return msg.From.ID, true
+}
+
+// rememberChat extracts facts from a finished chat without holding up the reply.
+func rememberChat(ctx context.Context, keeper MemoryKeeper, msg *models.Message, chat domain.Chat) {
+	userID, ok := memoryOwner(msg)
+	if keeper == nil || !ok || len(chat.Messages) == 0 {
+		return
+	}
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), rememberTimeout)
+		defer cancel()
+
+		if err := keeper.Remember(ctx, userID, chat); err != nil {
+			slog.ErrorContext(ctx, "Failed to remember chat", logger.Err(err))
+		}
+	}()
+}
+
+// recallMemories adds the memories relevant to the first question of a new chat to its system prompt.
+func recallMemories(ctx context.Context, keeper MemoryKeeper, msg *models.Message, chat *domain.Chat, question string) {
+	userID, ok := memoryOwner(msg)
+	if keeper == nil || !ok {
+		return
+	}
+
+	memories, err := keeper.Recall(ctx, userID, *chat, question)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to recall memories", logger.Err(err))
+		return
+	}
+	if len(memories) == 0 {
+		return
+	}
+
+	var sb strings.Builder
+	sb.WriteString("Что известно о пользователе из прошлых разговоров:")
+	for _, m := range memories {
+		sb.WriteString("\n- " + m.Content)
+	}
+
+	chat.SystemPrompt = strings.TrimSpace(chat.SystemPrompt + "\n\n" + sb.String())
+
+	slog.InfoContext(ctx, "Recalled memories for the new chat", "count", len(memories))
+}
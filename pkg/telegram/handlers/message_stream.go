@@ -0,0 +1,156 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/dskvich/chatgpt-telegram-bot/pkg/logger"
+	"github.com/dskvich/chatgpt-telegram-bot/pkg/render"
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+)
+
+const (
+	maxTelegramMessageLength = 4096
+	streamPlaceholderText    = "⏳"
+
+	// Telegram allows about one message per second in a private chat and 20 per minute in a group.
+	privateChatEditInterval = time.Second
+	groupChatEditInterval   = 3 * time.Second
+)
+
+// messageStream renders a growing markdown answer into Telegram messages. It edits the current
+// message at most once per interval and rolls over into a new message when the rendered text
+// no longer fits into a single one. The reply markup is attached to every finished message.
+type messageStream struct {
+	b           *bot.Bot
+	chatID      int64
+	topicID     int
+	interval    time.Duration
+	replyMarkup models.ReplyMarkup
+
+	messageID int
+	content   string
+	sent      string
+	lastEdit  time.Time
+}
+
+func newMessageStream(b *bot.Bot, chat models.Chat, topicID int, replyMarkup models.ReplyMarkup) *messageStream {
+	interval := groupChatEditInterval
+	if chat.Type == models.ChatTypePrivate {
+		interval = privateChatEditInterval
+	}
+
+	return &messageStream{
+		b:           b,
+		chatID:      chat.ID,
+		topicID:     topicID,
+		interval:    interval,
+		replyMarkup: replyMarkup,
+	}
+}
+
+// Start sends the placeholder message that is edited as chunks arrive.
+func (s *messageStream) Start(ctx context.Context) error {
+	msg, err := s.b.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID:          s.chatID,
+		MessageThreadID: s.topicID,
+		Text:            streamPlaceholderText,
+	})
+	if err != nil {
+		return fmt.Errorf("sending placeholder message: %w", err)
+	}
+
+	s.messageID = msg.ID
+	s.sent = streamPlaceholderText
+	s.lastEdit = time.Now()
+
+	return nil
+}
+
+// Write appends a chunk of the answer and updates Telegram if the throttle interval has passed.
+func (s *messageStream) Write(ctx context.Context, delta string) {
+	s.content += delta
+
+	for utf8.RuneCountInString(render.ToHTML(render.CloseCodeFences(s.content))) > maxTelegramMessageLength {
+		head, tail := render.SplitMarkdown(s.content, maxTelegramMessageLength)
+		s.edit(ctx, render.ToHTML(head), true)
+
+		msg, err := s.b.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID:          s.chatID,
+			MessageThreadID: s.topicID,
+			Text:            streamPlaceholderText,
+		})
+		if err != nil {
+			slog.ErrorContext(ctx, "Failed to send rollover message", logger.Err(err))
+			return
+		}
+
+		s.messageID = msg.ID
+		s.content = tail
+		s.sent = streamPlaceholderText
+	}
+
+	if time.Since(s.lastEdit) >= s.interval {
+		s.edit(ctx, render.ToHTML(render.CloseCodeFences(s.content)), false)
+	}
+}
+
+// Close flushes whatever is left of the answer regardless of the throttle interval.
+func (s *messageStream) Close(ctx context.Context) {
+	s.edit(ctx, render.ToHTML(render.CloseCodeFences(s.content)), true)
+}
+
+// Abort reports a failure. The placeholder is replaced when nothing has been streamed yet,
+// otherwise the partial answer is kept and the failure is sent as a separate message.
+func (s *messageStream) Abort(ctx context.Context, text string) {
+	if s.content == "" && s.messageID != 0 {
+		if _, err := s.b.EditMessageText(ctx, &bot.EditMessageTextParams{
+			ChatID:    s.chatID,
+			MessageID: s.messageID,
+			Text:      text,
+		}); err == nil {
+			return
+		}
+	}
+
+	s.Close(ctx)
+
+	s.b.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID:          s.chatID,
+		MessageThreadID: s.topicID,
+		Text:            text,
+	})
+}
+
+// edit updates the current message. A final edit attaches the reply markup, so it is sent
+// even when the text has not changed since the last throttled edit.
+func (s *messageStream) edit(ctx context.Context, htmlText string, final bool) {
+	withMarkup := final && s.replyMarkup != nil
+	if s.messageID == 0 || strings.TrimSpace(htmlText) == "" || htmlText == s.sent && !withMarkup {
+		return
+	}
+
+	params := &bot.EditMessageTextParams{
+		ChatID:    s.chatID,
+		MessageID: s.messageID,
+		Text:      htmlText,
+		ParseMode: models.ParseModeHTML,
+	}
+	if withMarkup {
+		params.ReplyMarkup = s.replyMarkup
+	}
+
+	_, err := s.b.EditMessageText(ctx, params)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to edit streamed message", logger.Err(err))
+		return
+	}
+
+	s.sent = htmlText
+	s.lastEdit = time.Now()
+}
@@ -0,0 +1,141 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/dskvich/chatgpt-telegram-bot/pkg/domain"
+	"github.com/dskvich/chatgpt-telegram-bot/pkg/logger"
+	"github.com/dskvich/chatgpt-telegram-bot/pkg/render"
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+)
+
+type alternativesStore interface {
+	Save(alternatives domain.Alternatives)
+	Take(chatID int64, topicID int) (domain.Alternatives, bool)
+}
+
+type pickAlternativeChatProvider interface {
+	Get(chatID int64, topicID int) (domain.Chat, time.Time, bool)
+	Save(chat domain.Chat)
+}
+
+// sendAlternatives sends every choice as a numbered answer with a button that picks it and
+// returns the IDs of the messages carrying the buttons. Each answer lists the knowledge base
+// sources it cites.
+func sendAlternatives(
+	ctx context.Context,
+	b *bot.Bot,
+	chatID int64,
+	topicID int,
+	choices []domain.Message,
+	sources []domain.KnowledgeChunk,
+) ([]int, error) {
+	messageIDs := make([]int, 0, len(choices))
+
+	for i, choice := range choices {
+		content := fmt.Sprintf("**Вариант %d**\n\n%s", i+1, choice.ContentParts[0].Data)
+		if footer := citedSources(choice.ContentParts[0].Data, sources); footer != "" {
+			content += "\n\n" + footer
+		}
+
+		var chunks []string
+		for utf8.RuneCountInString(render.ToHTML(content)) > maxTelegramMessageLength {
+			head, tail := render.SplitMarkdown(content, maxTelegramMessageLength)
+			chunks = append(chunks, render.ToHTML(head))
+			content = tail
+		}
+		chunks = append(chunks, render.ToHTML(content))
+
+		for j, chunk := range chunks {
+			params := &bot.SendMessageParams{
+				ChatID:          chatID,
+				MessageThreadID: topicID,
+				Text:            chunk,
+				ParseMode:       models.ParseModeHTML,
+			}
+			if j == len(chunks)-1 {
+				params.ReplyMarkup = &models.InlineKeyboardMarkup{
+					InlineKeyboard: [][]models.InlineKeyboardButton{{{
+						Text:         fmt.Sprintf("✅ Выбрать вариант %d", i+1),
+						CallbackData: domain.PickAlternativeCallbackPrefix + strconv.Itoa(i),
+					}}},
+				}
+			}
+
+			msg, err := b.SendMessage(ctx, params)
+			if err != nil {
+				return nil, fmt.Errorf("sending alternative %d: %w", i+1, err)
+			}
+			if j == len(chunks)-1 {
+				messageIDs = append(messageIDs, msg.ID)
+			}
+		}
+	}
+
+	return messageIDs, nil
+}
+
+// PickAlternative appends the chosen answer to the chat history. The choice is refused when the
+// chat has changed since the alternatives were generated.
+func PickAlternative(chatProvider pickAlternativeChatProvider, store alternativesStore) bot.HandlerFunc {
+	answer := func(ctx context.Context, b *bot.Bot, update *models.Update, text string) {
+		b.AnswerCallbackQuery(ctx, &bot.AnswerCallbackQueryParams{
+			CallbackQueryID: update.CallbackQuery.ID,
+			Text:            text,
+			ShowAlert:       false,
+		})
+	}
+
+	return func(ctx context.Context, b *bot.Bot, update *models.Update) {
+		msg := update.CallbackQuery.Message.Message
+		chatID := msg.Chat.ID
+		topicID := msg.MessageThreadID
+
+		index, err := strconv.Atoi(strings.TrimPrefix(update.CallbackQuery.Data, domain.PickAlternativeCallbackPrefix))
+		if err != nil {
+			answer(ctx, b, update, "❌ Не удалось определить вариант")
+			return
+		}
+
+		alternatives, ok := store.Take(chatID, topicID)
+		if !ok || index < 0 || index >= len(alternatives.Choices) {
+			answer(ctx, b, update, "⚠️ Эти варианты уже выбраны или устарели")
+			return
+		}
+
+		_, updatedAt, _ := chatProvider.Get(chatID, topicID)
+		stale := !updatedAt.Equal(alternatives.ChatUpdatedAt)
+
+		if stale {
+			answer(ctx, b, update, "⚠️ В чате уже есть новые сообщения, варианты устарели")
+		} else {
+			chat := alternatives.Chat
+			chat.Messages = append(chat.Messages, alternatives.Choices[index])
+			chatProvider.Save(chat)
+
+			answer(ctx, b, update, fmt.Sprintf("✅ Вариант %d добавлен в историю чата", index+1))
+		}
+
+		for i, messageID := range alternatives.MessageIDs {
+			var markup models.ReplyMarkup = &models.InlineKeyboardMarkup{InlineKeyboard: [][]models.InlineKeyboardButton{}}
+			if i == index && !stale {
+				markup = speakKeyboard
+			}
+
+			if _, err := b.EditMessageReplyMarkup(ctx, &bot.EditMessageReplyMarkupParams{
+				ChatID:      chatID,
+				MessageID:   messageID,
+				ReplyMarkup: markup,
+			}); err != nil {
+				slog.ErrorContext(ctx, "Failed to update alternative buttons", logger.Err(err))
+			}
+		}
+	}
+}
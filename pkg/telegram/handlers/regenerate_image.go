@@ -3,27 +3,46 @@ package handlers
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
 	"strconv"
 	"strings"
 
 	"github.com/dskvich/chatgpt-telegram-bot/pkg/domain"
+	"github.com/dskvich/chatgpt-telegram-bot/pkg/logger"
+	"github.com/dskvich/chatgpt-telegram-bot/pkg/telegram/errtext"
 	"github.com/go-telegram/bot"
 	"github.com/go-telegram/bot/models"
+	"github.com/samber/lo"
 )
 
 type regenerateImagePromptProvider interface {
 	GetByID(ctx context.Context, id int64) (string, error)
 }
 
+type regenerateImageSettingsProvider interface {
+	Get(ctx context.Context, chatID int64, topicID int) (*domain.Settings, error)
+}
+
 type regenerateImageProvider interface {
-	GenerateImage(ctx context.Context, prompt string) ([]byte, error)
+	GenerateImage(ctx context.Context, prompt string, settings domain.ImageSettings) ([]byte, error)
+}
+
+type regenerateImageSaver interface {
+	Save(chatID int64, topicID int, image []byte)
+}
+
+type regenerateImageUsageSaver interface {
+	Save(ctx context.Context, record domain.UsageRecord) error
 }
 
 func RegenerateImage(
+	settingsProvider regenerateImageSettingsProvider,
 	promptProvider regenerateImagePromptProvider,
 	imageProvider regenerateImageProvider,
+	imageSaver regenerateImageSaver,
+	usageSaver regenerateImageUsageSaver,
 ) bot.HandlerFunc {
 	const moreButtonText = "Еще"
 
@@ -71,22 +90,50 @@ func RegenerateImage(
 
 		slog.InfoContext(ctx, "Prompt fetched", "prompt", prompt)
 
-		imageData, err := imageProvider.GenerateImage(ctx, prompt)
+		settings, err := settingsProvider.Get(ctx, chatID, topicID)
+		if err != nil && !errors.Is(err, domain.ErrNotFound) {
+			b.SendMessage(ctx, &bot.SendMessageParams{
+				ChatID:          chatID,
+				MessageThreadID: topicID,
+				Text:            fmt.Sprintf("❌ Не удалось получить настройки: %s", err),
+			})
+			return
+		}
+
+		settings, _ = lo.Coalesce(settings, &domain.Settings{})
+		imageSettings := settings.ImageSettings()
+
+		imageData, err := imageProvider.GenerateImage(ctx, prompt, imageSettings)
 		if err != nil {
 			b.SendMessage(ctx, &bot.SendMessageParams{
 				ChatID:          chatID,
 				MessageThreadID: topicID,
-				Text:            fmt.Sprintf("❌ Не удалось сгенерировать изображение: %s", err),
+				Text:            errtext.Format(ctx, "Не удалось сгенерировать изображение", err),
 			})
 			return
 		}
 
 		slog.InfoContext(ctx, "Image generated", "size", len(imageData))
 
+		if err := usageSaver.Save(ctx, domain.UsageRecord{
+			UserID:       update.CallbackQuery.From.ID,
+			ChatID:       chatID,
+			TopicID:      topicID,
+			Model:        string(imageSettings.Model),
+			ImageSize:    imageSettings.Size,
+			ImageQuality: imageSettings.Quality,
+			Usage:        domain.Usage{Images: 1},
+		}); err != nil {
+			slog.ErrorContext(ctx, "Failed to save usage", logger.Err(err))
+		}
+
+		imageSaver.Save(chatID, topicID, imageData)
+
 		kb := &models.InlineKeyboardMarkup{
 			InlineKeyboard: [][]models.InlineKeyboardButton{
 				{
 					{Text: moreButtonText, CallbackData: domain.GenImageCallbackPrefix + strconv.FormatInt(promptID, 10)},
+					refineImageButton,
 				},
 			},
 		}
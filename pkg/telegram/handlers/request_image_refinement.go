@@ -0,0 +1,35 @@
+package handlers
+
+import (
+	"context"
+
+	"github.com/dskvich/chatgpt-telegram-bot/pkg/domain"
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+)
+
+type RequestImageRefinementStateProvider interface {
+	Save(chatID int64, topicID int, state domain.State)
+}
+
+func RequestImageRefinement(provider RequestImageRefinementStateProvider) bot.HandlerFunc {
+	return func(ctx context.Context, b *bot.Bot, update *models.Update) {
+		chatID := update.CallbackQuery.Message.Message.Chat.ID
+		topicID := update.CallbackQuery.Message.Message.MessageThreadID
+
+		b.AnswerCallbackQuery(ctx, &bot.AnswerCallbackQueryParams{
+			CallbackQueryID: update.CallbackQuery.ID,
+			ShowAlert:       false,
+		})
+
+		provider.Save(chatID, topicID, domain.StateRefineImage)
+
+		b.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID:          chatID,
+			MessageThreadID: topicID,
+			Text:            "✏️ Опишите, что изменить на изображении:",
+		})
+	}
+}
+
+var refineImageButton = models.InlineKeyboardButton{Text: "✏️ Изменить", CallbackData: domain.RefineImageCallbackPrefix}
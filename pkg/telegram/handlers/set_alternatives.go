@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/dskvich/chatgpt-telegram-bot/pkg/domain"
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"github.com/samber/lo"
+)
+
+type SetAlternativesSettingsProvider interface {
+	Get(ctx context.Context, chatID int64, topicID int) (*domain.Settings, error)
+	Save(ctx context.Context, settings domain.Settings) error
+}
+
+func SetAlternatives(provider SetAlternativesSettingsProvider, supportedCounts []int) bot.HandlerFunc {
+	parseCount := func(countRaw string) (int, error) {
+		if !strings.HasPrefix(countRaw, domain.SetAlternativesCallbackPrefix) {
+			return 0, fmt.Errorf("invalid format, expected prefix '%s'", domain.SetAlternativesCallbackPrefix)
+		}
+
+		count, err := strconv.Atoi(strings.TrimPrefix(countRaw, domain.SetAlternativesCallbackPrefix))
+		if err != nil {
+			return 0, err
+		}
+
+		if lo.Contains(supportedCounts, count) {
+			return count, nil
+		}
+
+		return 0, errors.New("unsupported alternatives option")
+	}
+
+	return func(ctx context.Context, b *bot.Bot, update *models.Update) {
+		chatID := update.CallbackQuery.Message.Message.Chat.ID
+		topicID := update.CallbackQuery.Message.Message.MessageThreadID
+
+		b.AnswerCallbackQuery(ctx, &bot.AnswerCallbackQueryParams{
+			CallbackQueryID: update.CallbackQuery.ID,
+			ShowAlert:       false,
+		})
+
+		count, err := parseCount(update.CallbackQuery.Data)
+		if err != nil {
+			b.SendMessage(ctx, &bot.SendMessageParams{
+				ChatID:          chatID,
+				MessageThreadID: topicID,
+				Text:            fmt.Sprintf("❌ Не удалось извлечь количество вариантов: %s", err),
+			})
+			return
+		}
+
+		settings, err := provider.Get(ctx, chatID, topicID)
+		if err != nil && !errors.Is(err, domain.ErrNotFound) {
+			b.SendMessage(ctx, &bot.SendMessageParams{
+				ChatID:          chatID,
+				MessageThreadID: topicID,
+				Text:            fmt.Sprintf("❌ Не удалось получить настройки: %s", err),
+			})
+			return
+		}
+
+		settings, _ = lo.Coalesce(settings, &domain.Settings{ChatID: chatID, TopicID: topicID})
+		settings.Alternatives = count
+
+		if err := provider.Save(ctx, *settings); err != nil {
+			b.SendMessage(ctx, &bot.SendMessageParams{
+				ChatID:          chatID,
+				MessageThreadID: topicID,
+				Text:            fmt.Sprintf("❌ Не удалось сохранить настройки: %s", err),
+			})
+			return
+		}
+
+		b.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID:          chatID,
+			MessageThreadID: topicID,
+			Text:            "✅ Варианты ответа: " + alternativesName(count),
+		})
+	}
+}
@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/dskvich/chatgpt-telegram-bot/pkg/domain"
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"github.com/samber/lo"
+)
+
+type SetHistoryDepthSettingsProvider interface {
+	Get(ctx context.Context, chatID int64, topicID int) (*domain.Settings, error)
+	Save(ctx context.Context, settings domain.Settings) error
+}
+
+func SetHistoryDepth(provider SetHistoryDepthSettingsProvider, supportedDepthOptions []int) bot.HandlerFunc {
+	parseDepth := func(depthRaw string) (int, error) {
+		if !strings.HasPrefix(depthRaw, domain.SetHistoryDepthCallbackPrefix) {
+			return 0, fmt.Errorf("invalid format, expected prefix '%s'", domain.SetHistoryDepthCallbackPrefix)
+		}
+
+		depth, err := strconv.Atoi(strings.TrimPrefix(depthRaw, domain.SetHistoryDepthCallbackPrefix))
+		if err != nil {
+			return 0, err
+		}
+
+		if lo.Contains(supportedDepthOptions, depth) {
+			return depth, nil
+		}
+
+		return 0, errors.New("unsupported history depth option")
+	}
+
+	return func(ctx context.Context, b *bot.Bot, update *models.Update) {
+		chatID := update.CallbackQuery.Message.Message.Chat.ID
+		topicID := update.CallbackQuery.Message.Message.MessageThreadID
+
+		b.AnswerCallbackQuery(ctx, &bot.AnswerCallbackQueryParams{
+			CallbackQueryID: update.CallbackQuery.ID,
+			ShowAlert:       false,
+		})
+
+		depth, err := parseDepth(update.CallbackQuery.Data)
+		if err != nil {
+			b.SendMessage(ctx, &bot.SendMessageParams{
+				ChatID:          chatID,
+				MessageThreadID: topicID,
+				Text:            fmt.Sprintf("❌ Не удалось извлечь глубину истории: %s", err),
+			})
+			return
+		}
+
+		settings, err := provider.Get(ctx, chatID, topicID)
+		if err != nil && !errors.Is(err, domain.ErrNotFound) {
+			b.SendMessage(ctx, &bot.SendMessageParams{
+				ChatID:          chatID,
+				MessageThreadID: topicID,
+				Text:            fmt.Sprintf("❌ Не удалось получить настройки: %s", err),
+			})
+			return
+		}
+
+		settings, _ = lo.Coalesce(settings, &domain.Settings{ChatID: chatID, TopicID: topicID})
+		settings.MaxHistoryDepth = depth
+
+		if err := provider.Save(ctx, *settings); err != nil {
+			b.SendMessage(ctx, &bot.SendMessageParams{
+				ChatID:          chatID,
+				MessageThreadID: topicID,
+				Text:            fmt.Sprintf("❌ Не удалось сохранить настройки: %s", err),
+			})
+			return
+		}
+
+		b.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID:          chatID,
+			MessageThreadID: topicID,
+			Text:            "✅ Глубина истории установлена: " + lo.Ternary(depth == 0, "без ограничений", strconv.Itoa(depth)),
+		})
+	}
+}
@@ -0,0 +1,89 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/dskvich/chatgpt-telegram-bot/pkg/domain"
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"github.com/samber/lo"
+)
+
+type SetImageSettingsProvider interface {
+	Get(ctx context.Context, chatID int64, topicID int) (*domain.Settings, error)
+	Save(ctx context.Context, settings domain.Settings) error
+}
+
+// SetImageModel stores the image model and asks for the image size next.
+func SetImageModel(provider SetImageSettingsProvider, supportedImageModels []domain.ImageModel) bot.HandlerFunc {
+	parseImageModel := func(modelRaw string) (domain.ImageModel, error) {
+		if !strings.HasPrefix(modelRaw, domain.SetImageModelCallbackPrefix) {
+			return "", fmt.Errorf("invalid format, expected prefix '%s'", domain.SetImageModelCallbackPrefix)
+		}
+
+		model := domain.ImageModel(strings.TrimPrefix(modelRaw, domain.SetImageModelCallbackPrefix))
+
+		if lo.Contains(supportedImageModels, model) {
+			return model, nil
+		}
+
+		return "", errors.New("unsupported model")
+	}
+
+	return func(ctx context.Context, b *bot.Bot, update *models.Update) {
+		chatID := update.CallbackQuery.Message.Message.Chat.ID
+		topicID := update.CallbackQuery.Message.Message.MessageThreadID
+
+		b.AnswerCallbackQuery(ctx, &bot.AnswerCallbackQueryParams{
+			CallbackQueryID: update.CallbackQuery.ID,
+			ShowAlert:       false,
+		})
+
+		model, err := parseImageModel(update.CallbackQuery.Data)
+		if err != nil {
+			b.SendMessage(ctx, &bot.SendMessageParams{
+				ChatID:          chatID,
+				MessageThreadID: topicID,
+				Text:            fmt.Sprintf("❌ Не удалось извлечь модель изображений: %s", err),
+			})
+			return
+		}
+
+		settings, err := provider.Get(ctx, chatID, topicID)
+		if err != nil && !errors.Is(err, domain.ErrNotFound) {
+			b.SendMessage(ctx, &bot.SendMessageParams{
+				ChatID:          chatID,
+				MessageThreadID: topicID,
+				Text:            fmt.Sprintf("❌ Не удалось получить настройки: %s", err),
+			})
+			return
+		}
+
+		settings, _ = lo.Coalesce(settings, &domain.Settings{ChatID: chatID, TopicID: topicID})
+
+		// Sizes and qualities differ between models, so the previous choice is reset to the model defaults.
+		imageSettings := domain.NewImageSettings(model, settings.ImageSize, settings.ImageQuality)
+		settings.ImageModel = string(imageSettings.Model)
+		settings.ImageSize = imageSettings.Size
+		settings.ImageQuality = imageSettings.Quality
+
+		if err := provider.Save(ctx, *settings); err != nil {
+			b.SendMessage(ctx, &bot.SendMessageParams{
+				ChatID:          chatID,
+				MessageThreadID: topicID,
+				Text:            fmt.Sprintf("❌ Не удалось сохранить настройки: %s", err),
+			})
+			return
+		}
+
+		b.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID:          chatID,
+			MessageThreadID: topicID,
+			Text:            "✅ Модель установлена: " + string(model) + "\n\n⚙️ Выберите размер изображения:",
+			ReplyMarkup:     imageSizesKeyboard(model),
+		})
+	}
+}
@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/dskvich/chatgpt-telegram-bot/pkg/domain"
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"github.com/samber/lo"
+)
+
+// SetImageSize stores the image size and asks for the quality when the model supports it.
+func SetImageSize(provider SetImageSettingsProvider) bot.HandlerFunc {
+	return func(ctx context.Context, b *bot.Bot, update *models.Update) {
+		chatID := update.CallbackQuery.Message.Message.Chat.ID
+		topicID := update.CallbackQuery.Message.Message.MessageThreadID
+
+		b.AnswerCallbackQuery(ctx, &bot.AnswerCallbackQueryParams{
+			CallbackQueryID: update.CallbackQuery.ID,
+			ShowAlert:       false,
+		})
+
+		settings, err := provider.Get(ctx, chatID, topicID)
+		if err != nil && !errors.Is(err, domain.ErrNotFound) {
+			b.SendMessage(ctx, &bot.SendMessageParams{
+				ChatID:          chatID,
+				MessageThreadID: topicID,
+				Text:            fmt.Sprintf("❌ Не удалось получить настройки: %s", err),
+			})
+			return
+		}
+
+		settings, _ = lo.Coalesce(settings, &domain.Settings{ChatID: chatID, TopicID: topicID})
+		model := settings.ImageSettings().Model
+
+		size := domain.ImageSize(strings.TrimPrefix(update.CallbackQuery.Data, domain.SetImageSizeCallbackPrefix))
+		if !lo.Contains(domain.ImageSizes(model), size) {
+			b.SendMessage(ctx, &bot.SendMessageParams{
+				ChatID:          chatID,
+				MessageThreadID: topicID,
+				Text:            fmt.Sprintf("❌ Размер %s не поддерживается моделью %s", size, model),
+			})
+			return
+		}
+
+		settings.ImageModel = string(model)
+		settings.ImageSize = size
+
+		if err := provider.Save(ctx, *settings); err != nil {
+			b.SendMessage(ctx, &bot.SendMessageParams{
+				ChatID:          chatID,
+				MessageThreadID: topicID,
+				Text:            fmt.Sprintf("❌ Не удалось сохранить настройки: %s", err),
+			})
+			return
+		}
+
+		if len(domain.ImageQualities(model)) == 0 {
+			b.SendMessage(ctx, &bot.SendMessageParams{
+				ChatID:          chatID,
+				MessageThreadID: topicID,
+				Text:            "✅ Размер изображения установлен: " + string(size),
+			})
+			return
+		}
+
+		b.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID:          chatID,
+			MessageThreadID: topicID,
+			Text:            "✅ Размер изображения установлен: " + string(size) + "\n\n⚙️ Выберите качество изображения:",
+			ReplyMarkup:     imageQualitiesKeyboard(model),
+		})
+	}
+}
@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/dskvich/chatgpt-telegram-bot/pkg/domain"
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"github.com/samber/lo"
+)
+
+// SetParam applies a button of the /params keyboard and updates the message in place.
+func SetParam(provider ShowParamsSettingsProvider) bot.HandlerFunc {
+	parseAction := func(data string) (paramControl, string, error) {
+		name, action, ok := strings.Cut(strings.TrimPrefix(data, domain.SetParamCallbackPrefix), ":")
+		if !ok || !lo.Contains([]string{paramActionDec, paramActionInc, paramActionReset}, action) {
+			return paramControl{}, "", fmt.Errorf("invalid format: %s", data)
+		}
+
+		c, ok := findParamControl(name)
+		if !ok {
+			return paramControl{}, "", fmt.Errorf("unknown parameter: %s", name)
+		}
+
+		return c, action, nil
+	}
+
+	return func(ctx context.Context, b *bot.Bot, update *models.Update) {
+		msg := update.CallbackQuery.Message.Message
+		chatID := msg.Chat.ID
+		topicID := msg.MessageThreadID
+
+		b.AnswerCallbackQuery(ctx, &bot.AnswerCallbackQueryParams{
+			CallbackQueryID: update.CallbackQuery.ID,
+			ShowAlert:       false,
+		})
+
+		c, action, err := parseAction(update.CallbackQuery.Data)
+		if err != nil {
+			b.SendMessage(ctx, &bot.SendMessageParams{
+				ChatID:          chatID,
+				MessageThreadID: topicID,
+				Text:            fmt.Sprintf("❌ Не удалось прочитать параметр: %s", err),
+			})
+			return
+		}
+
+		settings, err := provider.Get(ctx, chatID, topicID)
+		if err != nil && !errors.Is(err, domain.ErrNotFound) {
+			b.SendMessage(ctx, &bot.SendMessageParams{
+				ChatID:          chatID,
+				MessageThreadID: topicID,
+				Text:            fmt.Sprintf("❌ Не удалось получить настройки: %s", err),
+			})
+			return
+		}
+
+		settings, _ = lo.Coalesce(settings, &domain.Settings{ChatID: chatID, TopicID: topicID})
+		c.adjust(&settings.Params, action, paramsModel(settings))
+
+		if err := provider.Save(ctx, *settings); err != nil {
+			b.SendMessage(ctx, &bot.SendMessageParams{
+				ChatID:          chatID,
+				MessageThreadID: topicID,
+				Text:            fmt.Sprintf("❌ Не удалось сохранить настройки: %s", err),
+			})
+			return
+		}
+
+		b.EditMessageText(ctx, &bot.EditMessageTextParams{
+			ChatID:      chatID,
+			MessageID:   msg.ID,
+			Text:        paramsText(&settings.Params),
+			ReplyMarkup: paramsKeyboard(&settings.Params),
+		})
+	}
+}
@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/dskvich/chatgpt-telegram-bot/pkg/domain"
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"github.com/samber/lo"
+)
+
+type SetReasoningEffortSettingsProvider interface {
+	Get(ctx context.Context, chatID int64, topicID int) (*domain.Settings, error)
+	Save(ctx context.Context, settings domain.Settings) error
+}
+
+func SetReasoningEffort(provider SetReasoningEffortSettingsProvider, supportedEfforts []domain.ReasoningEffort) bot.HandlerFunc {
+	parseEffort := func(effortRaw string) (domain.ReasoningEffort, error) {
+		if !strings.HasPrefix(effortRaw, domain.SetReasoningCallbackPrefix) {
+			return "", fmt.Errorf("invalid format, expected prefix '%s'", domain.SetReasoningCallbackPrefix)
+		}
+
+		effort := domain.ReasoningEffort(strings.TrimPrefix(effortRaw, domain.SetReasoningCallbackPrefix))
+
+		if lo.Contains(supportedEfforts, effort) {
+			return effort, nil
+		}
+
+		return "", errors.New("unsupported reasoning effort")
+	}
+
+	return func(ctx context.Context, b *bot.Bot, update *models.Update) {
+		chatID := update.CallbackQuery.Message.Message.Chat.ID
+		topicID := update.CallbackQuery.Message.Message.MessageThreadID
+
+		b.AnswerCallbackQuery(ctx, &bot.AnswerCallbackQueryParams{
+			CallbackQueryID: update.CallbackQuery.ID,
+			ShowAlert:       false,
+		})
+
+		effort, err := parseEffort(update.CallbackQuery.Data)
+		if err != nil {
+			b.SendMessage(ctx, &bot.SendMessageParams{
+				ChatID:          chatID,
+				MessageThreadID: topicID,
+				Text:            fmt.Sprintf("❌ Не удалось извлечь уровень рассуждений: %s", err),
+			})
+			return
+		}
+
+		settings, err := provider.Get(ctx, chatID, topicID)
+		if err != nil && !errors.Is(err, domain.ErrNotFound) {
+			b.SendMessage(ctx, &bot.SendMessageParams{
+				ChatID:          chatID,
+				MessageThreadID: topicID,
+				Text:            fmt.Sprintf("❌ Не удалось получить настройки: %s", err),
+			})
+			return
+		}
+
+		settings, _ = lo.Coalesce(settings, &domain.Settings{ChatID: chatID, TopicID: topicID})
+		settings.ReasoningEffort = effort
+
+		if err := provider.Save(ctx, *settings); err != nil {
+			b.SendMessage(ctx, &bot.SendMessageParams{
+				ChatID:          chatID,
+				MessageThreadID: topicID,
+				Text:            fmt.Sprintf("❌ Не удалось сохранить настройки: %s", err),
+			})
+			return
+		}
+
+		b.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID:          chatID,
+			MessageThreadID: topicID,
+			Text:            "✅ Уровень рассуждений установлен: " + reasoningEffortNames[effort],
+		})
+	}
+}
@@ -21,7 +21,7 @@ type SetTextModelChatClearer interface {
 	Clear(chatID int64, topicID int)
 }
 
-func SetTextModel(provider SetTextModelSettingsProvider, clearer SetTextModelChatClearer, supportedTextModels []string) bot.HandlerFunc {
+func SetTextModel(provider SetTextModelSettingsProvider, clearer SetTextModelChatClearer, textModels TextModelsProvider) bot.HandlerFunc {
 	parseTextModel := func(modelRaw string) (string, error) {
 		if !strings.HasPrefix(modelRaw, domain.SetTextModelCallbackPrefix) {
 			return "", fmt.Errorf("invalid format, expected prefix '%s'", domain.SetTextModelCallbackPrefix)
@@ -29,7 +29,7 @@ func SetTextModel(provider SetTextModelSettingsProvider, clearer SetTextModelCha
 
 		model := strings.TrimPrefix(modelRaw, domain.SetTextModelCallbackPrefix)
 
-		if lo.Contains(supportedTextModels, model) {
+		if lo.Contains(textModels.Models(), model) {
 			return model, nil
 		}
 
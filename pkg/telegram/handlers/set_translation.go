@@ -0,0 +1,110 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/dskvich/chatgpt-telegram-bot/pkg/domain"
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"github.com/samber/lo"
+)
+
+type SetTranslationSettingsProvider interface {
+	Get(ctx context.Context, chatID int64, topicID int) (*domain.Settings, error)
+	Save(ctx context.Context, settings domain.Settings) error
+}
+
+// SetTranslation turns translating every message into the chosen language on, or off.
+func SetTranslation(provider SetTranslationSettingsProvider, supportedLanguages []string) bot.HandlerFunc {
+	return updateTranslation(provider, supportedLanguages, func(data string, settings *domain.Settings) error {
+		if !strings.HasPrefix(data, domain.SetTranslateCallbackPrefix) {
+			return fmt.Errorf("invalid format, expected prefix '%s'", domain.SetTranslateCallbackPrefix)
+		}
+
+		language := strings.TrimPrefix(data, domain.SetTranslateCallbackPrefix)
+
+		if language != translateOffOption && !lo.Contains(supportedLanguages, language) {
+			return errors.New("unsupported language")
+		}
+
+		settings.TranslateTo = lo.Ternary(language == translateOffOption, "", language)
+		return nil
+	})
+}
+
+// SetVoiceTranslation switches between transcribing speech and translating it into English.
+func SetVoiceTranslation(provider SetTranslationSettingsProvider, supportedLanguages []string) bot.HandlerFunc {
+	return updateTranslation(provider, supportedLanguages, func(data string, settings *domain.Settings) error {
+		if !strings.HasPrefix(data, domain.SetVoiceTranslationCallbackPrefix) {
+			return fmt.Errorf("invalid format, expected prefix '%s'", domain.SetVoiceTranslationCallbackPrefix)
+		}
+
+		switch strings.TrimPrefix(data, domain.SetVoiceTranslationCallbackPrefix) {
+		case "on":
+			settings.VoiceTranslation = true
+		case "off":
+			settings.VoiceTranslation = false
+		default:
+			return errors.New("unsupported option")
+		}
+		return nil
+	})
+}
+
+// updateTranslation applies the pressed button to the settings and redraws the /translate message.
+func updateTranslation(
+	provider SetTranslationSettingsProvider,
+	supportedLanguages []string,
+	apply func(data string, settings *domain.Settings) error,
+) bot.HandlerFunc {
+	return func(ctx context.Context, b *bot.Bot, update *models.Update) {
+		msg := update.CallbackQuery.Message.Message
+		chatID := msg.Chat.ID
+		topicID := msg.MessageThreadID
+
+		b.AnswerCallbackQuery(ctx, &bot.AnswerCallbackQueryParams{
+			CallbackQueryID: update.CallbackQuery.ID,
+			ShowAlert:       false,
+		})
+
+		settings, err := provider.Get(ctx, chatID, topicID)
+		if err != nil && !errors.Is(err, domain.ErrNotFound) {
+			b.SendMessage(ctx, &bot.SendMessageParams{
+				ChatID:          chatID,
+				MessageThreadID: topicID,
+				Text:            fmt.Sprintf("❌ Не удалось получить настройки: %s", err),
+			})
+			return
+		}
+
+		settings, _ = lo.Coalesce(settings, &domain.Settings{ChatID: chatID, TopicID: topicID})
+
+		if err := apply(update.CallbackQuery.Data, settings); err != nil {
+			b.SendMessage(ctx, &bot.SendMessageParams{
+				ChatID:          chatID,
+				MessageThreadID: topicID,
+				Text:            fmt.Sprintf("❌ Не удалось извлечь настройку перевода: %s", err),
+			})
+			return
+		}
+
+		if err := provider.Save(ctx, *settings); err != nil {
+			b.SendMessage(ctx, &bot.SendMessageParams{
+				ChatID:          chatID,
+				MessageThreadID: topicID,
+				Text:            fmt.Sprintf("❌ Не удалось сохранить настройки: %s", err),
+			})
+			return
+		}
+
+		b.EditMessageText(ctx, &bot.EditMessageTextParams{
+			ChatID:      chatID,
+			MessageID:   msg.ID,
+			Text:        translationText(settings),
+			ReplyMarkup: translationKeyboard(settings, supportedLanguages),
+		})
+	}
+}
@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/dskvich/chatgpt-telegram-bot/pkg/domain"
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"github.com/samber/lo"
+)
+
+type SetVoiceSettingsProvider interface {
+	Get(ctx context.Context, chatID int64, topicID int) (*domain.Settings, error)
+	Save(ctx context.Context, settings domain.Settings) error
+}
+
+// SetVoice turns voice replies on with the chosen voice, or off.
+func SetVoice(provider SetVoiceSettingsProvider, supportedVoices []string) bot.HandlerFunc {
+	parseVoice := func(voiceRaw string) (string, error) {
+		if !strings.HasPrefix(voiceRaw, domain.SetVoiceCallbackPrefix) {
+			return "", fmt.Errorf("invalid format, expected prefix '%s'", domain.SetVoiceCallbackPrefix)
+		}
+
+		voice := strings.TrimPrefix(voiceRaw, domain.SetVoiceCallbackPrefix)
+
+		if voice == voiceOffOption || lo.Contains(supportedVoices, voice) {
+			return voice, nil
+		}
+
+		return "", errors.New("unsupported voice")
+	}
+
+	return func(ctx context.Context, b *bot.Bot, update *models.Update) {
+		chatID := update.CallbackQuery.Message.Message.Chat.ID
+		topicID := update.CallbackQuery.Message.Message.MessageThreadID
+
+		b.AnswerCallbackQuery(ctx, &bot.AnswerCallbackQueryParams{
+			CallbackQueryID: update.CallbackQuery.ID,
+			ShowAlert:       false,
+		})
+
+		voice, err := parseVoice(update.CallbackQuery.Data)
+		if err != nil {
+			b.SendMessage(ctx, &bot.SendMessageParams{
+				ChatID:          chatID,
+				MessageThreadID: topicID,
+				Text:            fmt.Sprintf("❌ Не удалось извлечь голос: %s", err),
+			})
+			return
+		}
+
+		settings, err := provider.Get(ctx, chatID, topicID)
+		if err != nil && !errors.Is(err, domain.ErrNotFound) {
+			b.SendMessage(ctx, &bot.SendMessageParams{
+				ChatID:          chatID,
+				MessageThreadID: topicID,
+				Text:            fmt.Sprintf("❌ Не удалось получить настройки: %s", err),
+			})
+			return
+		}
+
+		settings, _ = lo.Coalesce(settings, &domain.Settings{ChatID: chatID, TopicID: topicID})
+		settings.VoiceReply = voice != voiceOffOption
+		if settings.VoiceReply {
+			settings.Voice = voice
+		}
+
+		if err := provider.Save(ctx, *settings); err != nil {
+			b.SendMessage(ctx, &bot.SendMessageParams{
+				ChatID:          chatID,
+				MessageThreadID: topicID,
+				Text:            fmt.Sprintf("❌ Не удалось сохранить настройки: %s", err),
+			})
+			return
+		}
+
+		b.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID:          chatID,
+			MessageThreadID: topicID,
+			Text:            lo.Ternary(settings.VoiceReply, "✅ Голосовые ответы включены, голос: "+voice, "✅ Голосовые ответы выключены"),
+		})
+	}
+}
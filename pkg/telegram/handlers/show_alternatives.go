@@ -0,0 +1,37 @@
+package handlers
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/dskvich/chatgpt-telegram-bot/pkg/domain"
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"github.com/samber/lo"
+)
+
+func alternativesName(count int) string {
+	return lo.Ternary(count < 2, "Один ответ", strconv.Itoa(count)+" варианта")
+}
+
+func ShowAlternatives(supportedCounts []int) bot.HandlerFunc {
+	return func(ctx context.Context, b *bot.Bot, update *models.Update) {
+		chatID := update.Message.Chat.ID
+		topicID := update.Message.MessageThreadID
+
+		buttons := lo.Map(supportedCounts, func(count int, _ int) models.InlineKeyboardButton {
+			return models.InlineKeyboardButton{Text: alternativesName(count), CallbackData: domain.SetAlternativesCallbackPrefix + strconv.Itoa(count)}
+		})
+
+		kb := &models.InlineKeyboardMarkup{
+			InlineKeyboard: lo.Chunk(buttons, 2), // 2 button in a row
+		}
+
+		b.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID:          chatID,
+			MessageThreadID: topicID,
+			Text:            "⚙️ Выберите, сколько вариантов ответа предлагать. В историю чата попадет только выбранный:",
+			ReplyMarkup:     kb,
+		})
+	}
+}
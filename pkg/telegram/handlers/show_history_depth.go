@@ -0,0 +1,34 @@
+package handlers
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/dskvich/chatgpt-telegram-bot/pkg/domain"
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"github.com/samber/lo"
+)
+
+func ShowHistoryDepth(supportedDepthOptions []int) bot.HandlerFunc {
+	return func(ctx context.Context, b *bot.Bot, update *models.Update) {
+		chatID := update.Message.Chat.ID
+		topicID := update.Message.MessageThreadID
+
+		buttons := lo.Map(supportedDepthOptions, func(depth int, _ int) models.InlineKeyboardButton {
+			text := lo.Ternary(depth == 0, "∞", strconv.Itoa(depth))
+			return models.InlineKeyboardButton{Text: text, CallbackData: domain.SetHistoryDepthCallbackPrefix + strconv.Itoa(depth)}
+		})
+
+		kb := &models.InlineKeyboardMarkup{
+			InlineKeyboard: lo.Chunk(buttons, 10), // 10 buttons in a row
+		}
+
+		b.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID:          chatID,
+			MessageThreadID: topicID,
+			Text:            "⚙️ Выберите, сколько последних сообщений модель помнит в чате:",
+			ReplyMarkup:     kb,
+		})
+	}
+}
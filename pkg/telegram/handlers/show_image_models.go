@@ -3,19 +3,55 @@ package handlers
 import (
 	"context"
 
+	"github.com/dskvich/chatgpt-telegram-bot/pkg/domain"
 	"github.com/go-telegram/bot"
 	"github.com/go-telegram/bot/models"
+	"github.com/samber/lo"
 )
 
-func ShowImageModels() bot.HandlerFunc {
+var imageQualityNames = map[domain.ImageQuality]string{
+	domain.QualityStandard: "Стандарт",
+	domain.QualityHD:       "HD",
+}
+
+func ShowImageModels(supportedImageModels []domain.ImageModel) bot.HandlerFunc {
 	return func(ctx context.Context, b *bot.Bot, update *models.Update) {
 		chatID := update.Message.Chat.ID
 		topicID := update.Message.MessageThreadID
 
+		buttons := lo.Map(supportedImageModels, func(model domain.ImageModel, _ int) models.InlineKeyboardButton {
+			return models.InlineKeyboardButton{Text: string(model), CallbackData: domain.SetImageModelCallbackPrefix + string(model)}
+		})
+
+		kb := &models.InlineKeyboardMarkup{
+			InlineKeyboard: lo.Chunk(buttons, 2), // 2 button in a row
+		}
+
 		b.SendMessage(ctx, &bot.SendMessageParams{
 			ChatID:          chatID,
 			MessageThreadID: topicID,
-			Text:            "🚧 В разработке 🚧",
+			Text:            "⚙️ Выберите модель для генерации изображений:",
+			ReplyMarkup:     kb,
 		})
 	}
 }
+
+func imageSizesKeyboard(model domain.ImageModel) *models.InlineKeyboardMarkup {
+	buttons := lo.Map(domain.ImageSizes(model), func(size domain.ImageSize, _ int) models.InlineKeyboardButton {
+		return models.InlineKeyboardButton{Text: string(size), CallbackData: domain.SetImageSizeCallbackPrefix + string(size)}
+	})
+
+	return &models.InlineKeyboardMarkup{
+		InlineKeyboard: lo.Chunk(buttons, 3), // 3 button in a row
+	}
+}
+
+func imageQualitiesKeyboard(model domain.ImageModel) *models.InlineKeyboardMarkup {
+	buttons := lo.Map(domain.ImageQualities(model), func(quality domain.ImageQuality, _ int) models.InlineKeyboardButton {
+		return models.InlineKeyboardButton{Text: imageQualityNames[quality], CallbackData: domain.SetImageQualityCallbackPrefix + string(quality)}
+	})
+
+	return &models.InlineKeyboardMarkup{
+		InlineKeyboard: lo.Chunk(buttons, 2), // 2 button in a row
+	}
+}
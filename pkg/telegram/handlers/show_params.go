@@ -0,0 +1,260 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/dskvich/chatgpt-telegram-bot/pkg/domain"
+	"github.com/dskvich/chatgpt-telegram-bot/pkg/llm"
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"github.com/samber/lo"
+)
+
+type ShowParamsSettingsProvider interface {
+	Get(ctx context.Context, chatID int64, topicID int) (*domain.Settings, error)
+	Save(ctx context.Context, settings domain.Settings) error
+}
+
+const (
+	paramActionDec   = "dec"
+	paramActionInc   = "inc"
+	paramActionReset = "reset"
+)
+
+// paramControl adjusts one generation parameter. Every parameter is handled as a float;
+// unset means the model default.
+type paramControl struct {
+	name     string
+	title    string
+	min, max float64
+	step     float64
+	scale    bool                       // inc doubles and dec halves the value instead of adding step
+	integer  bool                       // only whole numbers are accepted
+	modelMax func(model string) float64 // optional, a lower max set by the model
+	initial  float64                    // value the first inc or dec starts from
+	get      func(p *domain.GenerationParams) (float64, bool)
+	set      func(p *domain.GenerationParams, v float64, ok bool)
+}
+
+func floatParam(field func(p *domain.GenerationParams) **float64) (
+	func(p *domain.GenerationParams) (float64, bool),
+	func(p *domain.GenerationParams, v float64, ok bool),
+) {
+	get := func(p *domain.GenerationParams) (float64, bool) {
+		if v := *field(p); v != nil {
+			return *v, true
+		}
+		return 0, false
+	}
+	set := func(p *domain.GenerationParams, v float64, ok bool) {
+		*field(p) = lo.Ternary(ok, lo.ToPtr(v), nil)
+	}
+	return get, set
+}
+
+var paramControls = func() []paramControl {
+	temperatureGet, temperatureSet := floatParam(func(p *domain.GenerationParams) **float64 { return &p.Temperature })
+	topPGet, topPSet := floatParam(func(p *domain.GenerationParams) **float64 { return &p.TopP })
+	presenceGet, presenceSet := floatParam(func(p *domain.GenerationParams) **float64 { return &p.PresencePenalty })
+	frequencyGet, frequencySet := floatParam(func(p *domain.GenerationParams) **float64 { return &p.FrequencyPenalty })
+
+	return []paramControl{
+		{name: "temperature", title: "Temperature", min: 0, max: 2, step: 0.1, initial: 1, get: temperatureGet, set: temperatureSet},
+		{name: "top_p", title: "Top P", min: 0, max: 1, step: 0.05, initial: 1, get: topPGet, set: topPSet},
+		{name: "presence", title: "Presence penalty", min: -2, max: 2, step: 0.1, get: presenceGet, set: presenceSet},
+		{name: "frequency", title: "Frequency penalty", min: -2, max: 2, step: 0.1, get: frequencyGet, set: frequencySet},
+		{
+			name: "max_tokens", title: "Max tokens", min: 256, max: 32768, scale: true, integer: true, initial: 4096,
+			modelMax: func(model string) float64 { return float64(llm.MaxOutputTokens(model)) },
+			get:      func(p *domain.GenerationParams) (float64, bool) { return float64(p.MaxTokens), p.MaxTokens > 0 },
+			set:      func(p *domain.GenerationParams, v float64, ok bool) { p.MaxTokens = lo.Ternary(ok, int(v), 0) },
+		},
+		{
+			name: "seed", title: "Seed", min: 0, max: math.MaxInt32, step: 1, integer: true,
+			get: func(p *domain.GenerationParams) (float64, bool) {
+				if p.Seed == nil {
+					return 0, false
+				}
+				return float64(*p.Seed), true
+			},
+			set: func(p *domain.GenerationParams, v float64, ok bool) { p.Seed = lo.Ternary(ok, lo.ToPtr(int64(v)), nil) },
+		},
+	}
+}()
+
+func findParamControl(name string) (paramControl, bool) {
+	return lo.Find(paramControls, func(c paramControl) bool { return c.name == name })
+}
+
+// maxFor returns the largest value of the parameter the model accepts.
+func (c paramControl) maxFor(model string) float64 {
+	if c.modelMax == nil {
+		return c.max
+	}
+	return min(c.max, c.modelMax(model))
+}
+
+// adjust applies an action from the keyboard to the parameter.
+func (c paramControl) adjust(p *domain.GenerationParams, action, model string) {
+	if action == paramActionReset {
+		c.set(p, 0, false)
+		return
+	}
+
+	v, ok := c.get(p)
+	switch {
+	case !ok:
+		v = c.initial
+	case c.scale && action == paramActionInc:
+		v *= 2
+	case c.scale:
+		v /= 2
+	case action == paramActionInc:
+		v += c.step
+	default:
+		v -= c.step
+	}
+
+	c.set(p, c.clamp(v, model), true)
+}
+
+func (c paramControl) clamp(v float64, model string) float64 {
+	return math.Round(min(max(v, c.min), c.maxFor(model))*100) / 100
+}
+
+func (c paramControl) format(p *domain.GenerationParams) string {
+	v, ok := c.get(p)
+	if !ok {
+		return "по умолчанию"
+	}
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+func paramsText(p *domain.GenerationParams) string {
+	var sb strings.Builder
+	sb.WriteString("🎛️ Параметры генерации:\n")
+	for _, c := range paramControls {
+		fmt.Fprintf(&sb, "• %s: %s\n", c.title, c.format(p))
+	}
+	sb.WriteString("\nТочное значение: /params <параметр> <значение|default>")
+	return sb.String()
+}
+
+func paramsKeyboard(p *domain.GenerationParams) *models.InlineKeyboardMarkup {
+	rows := lo.Map(paramControls, func(c paramControl, _ int) []models.InlineKeyboardButton {
+		data := func(action string) string {
+			return domain.SetParamCallbackPrefix + c.name + ":" + action
+		}
+		return []models.InlineKeyboardButton{
+			{Text: "➖", CallbackData: data(paramActionDec)},
+			{Text: c.title + ": " + c.format(p), CallbackData: data(paramActionReset)},
+			{Text: "➕", CallbackData: data(paramActionInc)},
+		}
+	})
+
+	return &models.InlineKeyboardMarkup{InlineKeyboard: rows}
+}
+
+// parse reads a value of the parameter. NaN and infinities are rejected, they cannot be sent to the API.
+func (c paramControl) parse(s string) (float64, error) {
+	if c.integer {
+		n, err := strconv.Atoi(s)
+		return float64(n), err
+	}
+
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, err
+	}
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return 0, errors.New("not a number")
+	}
+	return v, nil
+}
+
+// paramsModel is the text model the parameters are used with.
+func paramsModel(settings *domain.Settings) string {
+	return lo.CoalesceOrEmpty(settings.TextModel, domain.Gpt4oMiniModel)
+}
+
+// ShowParams shows the generation parameters of the chat with controls to adjust them.
+// "/params <name> <value|default>" sets a parameter to an exact value.
+func ShowParams(provider ShowParamsSettingsProvider) bot.HandlerFunc {
+	names := strings.Join(lo.Map(paramControls, func(c paramControl, _ int) string { return c.name }), ", ")
+	usageText := "Использование: /params <параметр> <значение|default>\nПараметры: " + names
+
+	parseValue := func(args []string, model string) (paramControl, float64, bool, error) {
+		const argsCount = 2
+		if len(args) != argsCount {
+			return paramControl{}, 0, false, errors.New(usageText)
+		}
+
+		c, ok := findParamControl(args[0])
+		if !ok {
+			return paramControl{}, 0, false, fmt.Errorf("unknown parameter: %s", args[0])
+		}
+
+		if args[1] == "default" {
+			return c, 0, false, nil
+		}
+
+		v, err := c.parse(args[1])
+		if err != nil || v < c.min || v > c.maxFor(model) {
+			return paramControl{}, 0, false, fmt.Errorf("%s must be between %g and %g for %s", c.name, c.min, c.maxFor(model), model)
+		}
+
+		return c, v, true, nil
+	}
+
+	return func(ctx context.Context, b *bot.Bot, update *models.Update) {
+		chatID := update.Message.Chat.ID
+		topicID := update.Message.MessageThreadID
+
+		settings, err := provider.Get(ctx, chatID, topicID)
+		if err != nil && !errors.Is(err, domain.ErrNotFound) {
+			b.SendMessage(ctx, &bot.SendMessageParams{
+				ChatID:          chatID,
+				MessageThreadID: topicID,
+				Text:            fmt.Sprintf("❌ Не удалось получить настройки: %s", err),
+			})
+			return
+		}
+
+		settings, _ = lo.Coalesce(settings, &domain.Settings{ChatID: chatID, TopicID: topicID})
+
+		if args := strings.Fields(update.Message.Text)[1:]; len(args) > 0 {
+			c, v, ok, err := parseValue(args, paramsModel(settings))
+			if err != nil {
+				b.SendMessage(ctx, &bot.SendMessageParams{
+					ChatID:          chatID,
+					MessageThreadID: topicID,
+					Text:            fmt.Sprintf("❌ Не удалось прочитать параметр: %s", err),
+				})
+				return
+			}
+
+			c.set(&settings.Params, v, ok)
+
+			if err := provider.Save(ctx, *settings); err != nil {
+				b.SendMessage(ctx, &bot.SendMessageParams{
+					ChatID:          chatID,
+					MessageThreadID: topicID,
+					Text:            fmt.Sprintf("❌ Не удалось сохранить настройки: %s", err),
+				})
+				return
+			}
+		}
+
+		b.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID:          chatID,
+			MessageThreadID: topicID,
+			Text:            paramsText(&settings.Params),
+			ReplyMarkup:     paramsKeyboard(&settings.Params),
+		})
+	}
+}
@@ -0,0 +1,49 @@
+package handlers
+
+import (
+	"testing"
+
+	"github.com/dskvich/chatgpt-telegram-bot/pkg/domain"
+	"github.com/samber/lo"
+)
+
+func TestParamControlAdjust(t *testing.T) {
+	tests := []struct {
+		name   string
+		param  string
+		params domain.GenerationParams
+		action string
+		model  string
+		want   float64
+		wantOK bool
+	}{
+		{"unset starts from the initial value", "temperature", domain.GenerationParams{}, paramActionInc, "gpt-4o", 1, true},
+		{"inc adds the step", "temperature", domain.GenerationParams{Temperature: lo.ToPtr(1.0)}, paramActionInc, "gpt-4o", 1.1, true},
+		{"result is rounded", "top_p", domain.GenerationParams{TopP: lo.ToPtr(0.9)}, paramActionInc, "gpt-4o", 0.95, true},
+		{"inc stops at the max", "temperature", domain.GenerationParams{Temperature: lo.ToPtr(1.95)}, paramActionInc, "gpt-4o", 2, true},
+		{"dec stops at the min", "temperature", domain.GenerationParams{Temperature: lo.ToPtr(0.0)}, paramActionDec, "gpt-4o", 0, true},
+		{"reset unsets", "temperature", domain.GenerationParams{Temperature: lo.ToPtr(0.5)}, paramActionReset, "gpt-4o", 0, false},
+		{"max tokens doubles", "max_tokens", domain.GenerationParams{MaxTokens: 4096}, paramActionInc, "gpt-4.1", 8192, true},
+		{"max tokens halves", "max_tokens", domain.GenerationParams{MaxTokens: 4096}, paramActionDec, "gpt-4.1", 2048, true},
+		{"max tokens stops at the model limit", "max_tokens", domain.GenerationParams{MaxTokens: 16384}, paramActionInc, "gpt-4o-mini", 16384, true},
+		{"max tokens stops at the control max", "max_tokens", domain.GenerationParams{MaxTokens: 32768}, paramActionInc, "gpt-4.1", 32768, true},
+		{"max tokens stops at the min", "max_tokens", domain.GenerationParams{MaxTokens: 256}, paramActionDec, "gpt-4.1", 256, true},
+		{"seed adds one", "seed", domain.GenerationParams{Seed: lo.ToPtr(int64(41))}, paramActionInc, "gpt-4o", 42, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, ok := findParamControl(tt.param)
+			if !ok {
+				t.Fatalf("no control for %q", tt.param)
+			}
+
+			params := tt.params
+			c.adjust(&params, tt.action, tt.model)
+
+			if got, gotOK := c.get(&params); got != tt.want || gotOK != tt.wantOK {
+				t.Errorf("%s after %s = %v, %v, want %v, %v", tt.param, tt.action, got, gotOK, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
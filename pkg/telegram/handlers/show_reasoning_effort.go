@@ -0,0 +1,39 @@
+package handlers
+
+import (
+	"context"
+
+	"github.com/dskvich/chatgpt-telegram-bot/pkg/domain"
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"github.com/samber/lo"
+)
+
+var reasoningEffortNames = map[domain.ReasoningEffort]string{
+	"":                           "По умолчанию",
+	domain.ReasoningEffortLow:    "Низкий",
+	domain.ReasoningEffortMedium: "Средний",
+	domain.ReasoningEffortHigh:   "Высокий",
+}
+
+func ShowReasoningEffort(supportedEfforts []domain.ReasoningEffort) bot.HandlerFunc {
+	return func(ctx context.Context, b *bot.Bot, update *models.Update) {
+		chatID := update.Message.Chat.ID
+		topicID := update.Message.MessageThreadID
+
+		buttons := lo.Map(supportedEfforts, func(effort domain.ReasoningEffort, _ int) models.InlineKeyboardButton {
+			return models.InlineKeyboardButton{Text: reasoningEffortNames[effort], CallbackData: domain.SetReasoningCallbackPrefix + string(effort)}
+		})
+
+		kb := &models.InlineKeyboardMarkup{
+			InlineKeyboard: lo.Chunk(buttons, 2), // 2 button in a row
+		}
+
+		b.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID:          chatID,
+			MessageThreadID: topicID,
+			Text:            "⚙️ Выберите уровень рассуждений для моделей o-серии:",
+			ReplyMarkup:     kb,
+		})
+	}
+}
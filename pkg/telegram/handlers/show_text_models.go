@@ -2,6 +2,8 @@ package handlers
 
 import (
 	"context"
+	"strconv"
+	"strings"
 
 	"github.com/dskvich/chatgpt-telegram-bot/pkg/domain"
 	"github.com/go-telegram/bot"
@@ -9,24 +11,78 @@ import (
 	"github.com/samber/lo"
 )
 
-func ShowTextModels(supportedTextModels []string) bot.HandlerFunc {
+const textModelsPageSize = 10
+
+type TextModelsProvider interface {
+	Models() []string
+}
+
+func ShowTextModels(provider TextModelsProvider) bot.HandlerFunc {
 	return func(ctx context.Context, b *bot.Bot, update *models.Update) {
 		chatID := update.Message.Chat.ID
 		topicID := update.Message.MessageThreadID
 
-		buttons := lo.Map(supportedTextModels, func(text string, _ int) models.InlineKeyboardButton {
-			return models.InlineKeyboardButton{Text: text, CallbackData: domain.SetTextModelCallbackPrefix + text}
-		})
-
-		kb := &models.InlineKeyboardMarkup{
-			InlineKeyboard: lo.Chunk(buttons, 2), // 2 button in a row
-		}
-
 		b.SendMessage(ctx, &bot.SendMessageParams{
 			ChatID:          chatID,
 			MessageThreadID: topicID,
 			Text:            "⚙️ Выберите текстовую модель GPT:",
-			ReplyMarkup:     kb,
+			ReplyMarkup:     textModelsKeyboard(provider.Models(), 0),
 		})
 	}
 }
+
+// ShowTextModelsPage switches the text models keyboard to another page.
+func ShowTextModelsPage(provider TextModelsProvider) bot.HandlerFunc {
+	return func(ctx context.Context, b *bot.Bot, update *models.Update) {
+		b.AnswerCallbackQuery(ctx, &bot.AnswerCallbackQueryParams{
+			CallbackQueryID: update.CallbackQuery.ID,
+			ShowAlert:       false,
+		})
+
+		msg := update.CallbackQuery.Message.Message
+		if msg == nil {
+			return
+		}
+
+		page, err := strconv.Atoi(strings.TrimPrefix(update.CallbackQuery.Data, domain.TextModelsPageCallbackPrefix))
+		if err != nil {
+			return
+		}
+
+		b.EditMessageReplyMarkup(ctx, &bot.EditMessageReplyMarkupParams{
+			ChatID:      msg.Chat.ID,
+			MessageID:   msg.ID,
+			ReplyMarkup: textModelsKeyboard(provider.Models(), page),
+		})
+	}
+}
+
+func textModelsKeyboard(textModels []string, page int) *models.InlineKeyboardMarkup {
+	pages := max((len(textModels)+textModelsPageSize-1)/textModelsPageSize, 1)
+	page = min(max(page, 0), pages-1)
+
+	pageModels := textModels[min(page*textModelsPageSize, len(textModels)):min((page+1)*textModelsPageSize, len(textModels))]
+
+	buttons := lo.Map(pageModels, func(text string, _ int) models.InlineKeyboardButton {
+		return models.InlineKeyboardButton{Text: text, CallbackData: domain.SetTextModelCallbackPrefix + text}
+	})
+
+	rows := lo.Chunk(buttons, 2) // 2 button in a row
+
+	if pages > 1 {
+		var nav []models.InlineKeyboardButton
+		if page > 0 {
+			nav = append(nav, models.InlineKeyboardButton{Text: "◀️", CallbackData: domain.TextModelsPageCallbackPrefix + strconv.Itoa(page-1)})
+		}
+		nav = append(nav, models.InlineKeyboardButton{
+			Text:         strconv.Itoa(page+1) + "/" + strconv.Itoa(pages),
+			CallbackData: domain.TextModelsPageCallbackPrefix + strconv.Itoa(page),
+		})
+		if page < pages-1 {
+			nav = append(nav, models.InlineKeyboardButton{Text: "▶️", CallbackData: domain.TextModelsPageCallbackPrefix + strconv.Itoa(page+1)})
+		}
+		rows = append(rows, nav)
+	}
+
+	return &models.InlineKeyboardMarkup{InlineKeyboard: rows}
+}
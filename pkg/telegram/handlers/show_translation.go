@@ -0,0 +1,116 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/dskvich/chatgpt-telegram-bot/pkg/domain"
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"github.com/samber/lo"
+)
+
+const (
+	translateOffOption      = "off"
+	maxTranslateLanguageLen = 32
+)
+
+type ShowTranslationSettingsProvider interface {
+	Get(ctx context.Context, chatID int64, topicID int) (*domain.Settings, error)
+	Save(ctx context.Context, settings domain.Settings) error
+}
+
+func translationText(settings *domain.Settings) string {
+	mode := lo.Ternary(settings.TranslateTo == "", "выключен, бот отвечает на сообщения", "все сообщения переводятся на "+settings.TranslateTo)
+	voice := lo.Ternary(settings.VoiceTranslation, "переводятся на английский", "распознаются как есть")
+
+	return fmt.Sprintf(`⚙️ Режим перевода: %s
+🎙 Голосовые: %s
+
+Выберите язык, на который переводить все сообщения, или укажите свой: /translate <язык>`, mode, voice)
+}
+
+func translationKeyboard(settings *domain.Settings, supportedLanguages []string) *models.InlineKeyboardMarkup {
+	buttons := lo.Map(supportedLanguages, func(language string, _ int) models.InlineKeyboardButton {
+		return models.InlineKeyboardButton{Text: language, CallbackData: domain.SetTranslateCallbackPrefix + language}
+	})
+
+	voiceButton := models.InlineKeyboardButton{
+		Text:         lo.Ternary(settings.VoiceTranslation, "🎙 Голосовые: перевод на английский ✅", "🎙 Голосовые: перевод на английский ❌"),
+		CallbackData: domain.SetVoiceTranslationCallbackPrefix + lo.Ternary(settings.VoiceTranslation, "off", "on"),
+	}
+
+	return &models.InlineKeyboardMarkup{
+		InlineKeyboard: append(lo.Chunk(buttons, 3), // 3 button in a row
+			[]models.InlineKeyboardButton{{Text: "🚫 Выключить перевод", CallbackData: domain.SetTranslateCallbackPrefix + translateOffOption}},
+			[]models.InlineKeyboardButton{voiceButton},
+		),
+	}
+}
+
+// ShowTranslation shows the translation mode of the chat. "/translate <language>" turns it on for any
+// language, "/translate off" turns it off.
+func ShowTranslation(provider ShowTranslationSettingsProvider, supportedLanguages []string) bot.HandlerFunc {
+	return func(ctx context.Context, b *bot.Bot, update *models.Update) {
+		chatID := update.Message.Chat.ID
+		topicID := update.Message.MessageThreadID
+
+		settings, err := provider.Get(ctx, chatID, topicID)
+		if err != nil && !errors.Is(err, domain.ErrNotFound) {
+			b.SendMessage(ctx, &bot.SendMessageParams{
+				ChatID:          chatID,
+				MessageThreadID: topicID,
+				Text:            fmt.Sprintf("❌ Не удалось получить настройки: %s", err),
+			})
+			return
+		}
+
+		settings, _ = lo.Coalesce(settings, &domain.Settings{ChatID: chatID, TopicID: topicID})
+
+		_, language, _ := strings.Cut(update.Message.Text, " ")
+		language = strings.TrimSpace(language)
+
+		if language == "" {
+			b.SendMessage(ctx, &bot.SendMessageParams{
+				ChatID:          chatID,
+				MessageThreadID: topicID,
+				Text:            translationText(settings),
+				ReplyMarkup:     translationKeyboard(settings, supportedLanguages),
+			})
+			return
+		}
+
+		if utf8.RuneCountInString(language) > maxTranslateLanguageLen {
+			b.SendMessage(ctx, &bot.SendMessageParams{
+				ChatID:          chatID,
+				MessageThreadID: topicID,
+				Text:            "❌ Слишком длинное название языка.",
+			})
+			return
+		}
+
+		settings.TranslateTo = lo.Ternary(strings.EqualFold(language, translateOffOption), "", language)
+
+		if err := provider.Save(ctx, *settings); err != nil {
+			b.SendMessage(ctx, &bot.SendMessageParams{
+				ChatID:          chatID,
+				MessageThreadID: topicID,
+				Text:            fmt.Sprintf("❌ Не удалось сохранить настройки: %s", err),
+			})
+			return
+		}
+
+		b.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID:          chatID,
+			MessageThreadID: topicID,
+			Text:            translationResult(settings.TranslateTo),
+		})
+	}
+}
+
+func translationResult(language string) string {
+	return lo.Ternary(language == "", "✅ Режим перевода выключен", "✅ Все сообщения будут переводиться на "+language)
+}
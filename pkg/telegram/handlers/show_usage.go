@@ -0,0 +1,74 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/dskvich/chatgpt-telegram-bot/pkg/domain"
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+)
+
+type ShowUsageProvider interface {
+	TotalByUser(ctx context.Context, userID int64, since time.Time) (domain.Usage, error)
+}
+
+func ShowUsage(provider ShowUsageProvider) bot.HandlerFunc {
+	startOfDay := func(t time.Time) time.Time {
+		y, m, d := t.Date()
+		return time.Date(y, m, d, 0, 0, 0, 0, t.Location())
+	}
+
+	startOfWeek := func(t time.Time) time.Time {
+		daysSinceMonday := (int(t.Weekday()) + 6) % 7
+		return startOfDay(t).AddDate(0, 0, -daysSinceMonday)
+	}
+
+	startOfMonth := func(t time.Time) time.Time {
+		y, m, _ := t.Date()
+		return time.Date(y, m, 1, 0, 0, 0, 0, t.Location())
+	}
+
+	return func(ctx context.Context, b *bot.Bot, update *models.Update) {
+		chatID := update.Message.Chat.ID
+		topicID := update.Message.MessageThreadID
+		userID := update.Message.From.ID
+
+		now := time.Now()
+		periods := []struct {
+			title string
+			since time.Time
+		}{
+			{"Сегодня", startOfDay(now)},
+			{"Эта неделя", startOfWeek(now)},
+			{"Этот месяц", startOfMonth(now)},
+		}
+
+		var sb strings.Builder
+		sb.WriteString("📊 Использование:\n")
+
+		for _, p := range periods {
+			usage, err := provider.TotalByUser(ctx, userID, p.since)
+			if err != nil {
+				b.SendMessage(ctx, &bot.SendMessageParams{
+					ChatID:          chatID,
+					MessageThreadID: topicID,
+					Text:            fmt.Sprintf("❌ Не удалось получить статистику: %s", err),
+				})
+				return
+			}
+
+			fmt.Fprintf(&sb, "\n%s:\n🔤 Токены: %d вх. / %d вых.\n🖼️ Изображения: %d\n🎙 Расшифровки: %d\n🔊 Озвучено символов: %d\n💵 Стоимость: $%.4f\n",
+				p.title, usage.PromptTokens, usage.CompletionTokens, usage.Images, usage.Transcriptions,
+				usage.SpeechCharacters, usage.Cost)
+		}
+
+		b.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID:          chatID,
+			MessageThreadID: topicID,
+			Text:            sb.String(),
+		})
+	}
+}
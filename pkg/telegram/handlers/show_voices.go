@@ -0,0 +1,36 @@
+package handlers
+
+import (
+	"context"
+
+	"github.com/dskvich/chatgpt-telegram-bot/pkg/domain"
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"github.com/samber/lo"
+)
+
+const voiceOffOption = "off"
+
+func ShowVoices(supportedVoices []string) bot.HandlerFunc {
+	return func(ctx context.Context, b *bot.Bot, update *models.Update) {
+		chatID := update.Message.Chat.ID
+		topicID := update.Message.MessageThreadID
+
+		buttons := lo.Map(supportedVoices, func(voice string, _ int) models.InlineKeyboardButton {
+			return models.InlineKeyboardButton{Text: voice, CallbackData: domain.SetVoiceCallbackPrefix + voice}
+		})
+
+		kb := &models.InlineKeyboardMarkup{
+			InlineKeyboard: append(lo.Chunk(buttons, 3), // 3 button in a row
+				[]models.InlineKeyboardButton{{Text: "🔇 Выключить", CallbackData: domain.SetVoiceCallbackPrefix + voiceOffOption}},
+			),
+		}
+
+		b.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID:          chatID,
+			MessageThreadID: topicID,
+			Text:            "⚙️ Выберите голос, которым бот будет озвучивать ответы:",
+			ReplyMarkup:     kb,
+		})
+	}
+}
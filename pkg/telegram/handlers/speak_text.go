@@ -0,0 +1,159 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/dskvich/chatgpt-telegram-bot/pkg/domain"
+	"github.com/dskvich/chatgpt-telegram-bot/pkg/logger"
+	"github.com/dskvich/chatgpt-telegram-bot/pkg/telegram/errtext"
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"github.com/samber/lo"
+)
+
+// maxSpeechInputLength is the longest text the speech endpoint accepts in one request.
+const maxSpeechInputLength = 4096
+
+type speechSynthesizer interface {
+	SynthesizeSpeech(ctx context.Context, text, voice string) ([]byte, error)
+}
+
+type speechConverter interface {
+	ConvertToOggOpus(ctx context.Context, inputPath string) (string, error)
+}
+
+type speakTextSettingsProvider interface {
+	Get(ctx context.Context, chatID int64, topicID int) (*domain.Settings, error)
+}
+
+type speakTextUsageSaver interface {
+	Save(ctx context.Context, record domain.UsageRecord) error
+}
+
+var speakKeyboard = &models.InlineKeyboardMarkup{
+	InlineKeyboard: [][]models.InlineKeyboardButton{
+		{{Text: "🔊 Озвучить", CallbackData: domain.SpeakCallbackPrefix}},
+	},
+}
+
+// SpeakText reads aloud the answer the "🔊 Озвучить" button is attached to.
+func SpeakText(
+	settingsProvider speakTextSettingsProvider,
+	synthesizer speechSynthesizer,
+	converter speechConverter,
+	usageSaver speakTextUsageSaver,
+) bot.HandlerFunc {
+	return func(ctx context.Context, b *bot.Bot, update *models.Update) {
+		defer b.AnswerCallbackQuery(ctx, &bot.AnswerCallbackQueryParams{
+			CallbackQueryID: update.CallbackQuery.ID,
+			ShowAlert:       false,
+		})
+
+		msg := update.CallbackQuery.Message.Message
+		if msg == nil || strings.TrimSpace(msg.Text) == "" {
+			return
+		}
+		chatID := msg.Chat.ID
+		topicID := msg.MessageThreadID
+
+		settings, err := settingsProvider.Get(ctx, chatID, topicID)
+		if err != nil && !errors.Is(err, domain.ErrNotFound) {
+			b.SendMessage(ctx, &bot.SendMessageParams{
+				ChatID:          chatID,
+				MessageThreadID: topicID,
+				Text:            fmt.Sprintf("❌ Не удалось получить настройки: %s", err),
+			})
+			return
+		}
+
+		settings, _ = lo.Coalesce(settings, &domain.Settings{})
+
+		if err := sendSpeech(ctx, b, synthesizer, converter, chatID, topicID, msg.Text, settings.Voice); err != nil {
+			b.SendMessage(ctx, &bot.SendMessageParams{
+				ChatID:          chatID,
+				MessageThreadID: topicID,
+				Text:            errtext.Format(ctx, "Не удалось озвучить ответ", err),
+			})
+			return
+		}
+
+		if err := usageSaver.Save(ctx, domain.UsageRecord{
+			UserID:  update.CallbackQuery.From.ID,
+			ChatID:  chatID,
+			TopicID: topicID,
+			Model:   domain.TTS1Model,
+			Usage:   domain.Usage{SpeechCharacters: utf8.RuneCountInString(msg.Text)},
+		}); err != nil {
+			slog.ErrorContext(ctx, "Failed to save usage", logger.Err(err))
+		}
+	}
+}
+
+// sendSpeech synthesizes the text, converts it into OGG/Opus and sends it as voice notes,
+// one per maxSpeechInputLength runes.
+func sendSpeech(
+	ctx context.Context,
+	b *bot.Bot,
+	synthesizer speechSynthesizer,
+	converter speechConverter,
+	chatID int64,
+	topicID int,
+	text, voice string,
+) error {
+	const (
+		speechTempDir      = "tmp/speech"
+		speechTempFilePerm = 0o644
+	)
+
+	voice = lo.CoalesceOrEmpty(voice, domain.DefaultVoice)
+
+	if err := os.MkdirAll(speechTempDir, os.ModePerm); err != nil {
+		return fmt.Errorf("unable to create temp directory: %w", err)
+	}
+
+	for _, chunk := range lo.ChunkString(text, maxSpeechInputLength) {
+		audio, err := synthesizer.SynthesizeSpeech(ctx, chunk, voice)
+		if err != nil {
+			return err
+		}
+
+		mp3Path := filepath.Join(speechTempDir, fmt.Sprintf("speech-%d.mp3", time.Now().UnixNano()))
+		if err := os.WriteFile(mp3Path, audio, speechTempFilePerm); err != nil {
+			return fmt.Errorf("unable to write speech file: %w", err)
+		}
+
+		oggPath, err := converter.ConvertToOggOpus(ctx, mp3Path)
+		os.Remove(mp3Path)
+		if err != nil {
+			return fmt.Errorf("unable to convert speech to OGG/Opus: %w", err)
+		}
+
+		voiceData, err := os.ReadFile(oggPath)
+		os.Remove(oggPath)
+		if err != nil {
+			return fmt.Errorf("unable to read voice file: %w", err)
+		}
+
+		if _, err := b.SendVoice(ctx, &bot.SendVoiceParams{
+			ChatID:          chatID,
+			MessageThreadID: topicID,
+			Voice: &models.InputFileUpload{
+				Filename: "voice.ogg",
+				Data:     bytes.NewReader(voiceData),
+			},
+		}); err != nil {
+			return fmt.Errorf("unable to send voice: %w", err)
+		}
+	}
+
+	return nil
+}
@@ -13,14 +13,27 @@ func Start() bot.HandlerFunc {
 
 🆕 **/new** — Начать новый чат
 ⏳ **/ttl** — Установить время жизни чата
+📜 **/history** — Сколько сообщений помнит модель
 📝 **/text_models** — Выбрать модель для текста
+🧠 **/reasoning** — Уровень рассуждений моделей o-серии
+🎛️ **/params** — Параметры генерации
+📑 **/drafts** — Несколько вариантов ответа на выбор
+💭 **/memories** — Что бот помнит о вас
+📚 **/kb** — База знаний чата
 🖼️ **/image_models** — Выбрать модель для картинок
 ⚙️ **/system_prompt** — Настроить системную инструкцию
+🔊 **/voice** — Голосовые ответы
+🌐 **/translate** — Режим перевода сообщений
+📊 **/usage** — Статистика использования
+💰 **/budget** — Бюджеты на расходы
+🛡️ **/moderation** — Настройки модерации
 
 🖊️ Просто задай мне вопрос — я помогу!
 🎨 Напиши "нарисуй ..." и я создам картинку.
-🎙 Отправь голосовое сообщение — я пойму.
+✏️ Отправь фото с подписью "нарисуй ..." или ответь на картинку — я её изменю.
+🎙 Отправь голосовое, аудио, кружок или видео — я распознаю речь.
 📷 Отправь картинку — я опишу её или отвечу на твои вопросы о ней.
+📄 Отправь документ (.txt, .md, код, .pdf, .docx) с вопросом в подписи — я отвечу по нему.
 
 Начнем? 🚀`
 
@@ -0,0 +1,30 @@
+package matchers
+
+import (
+	"github.com/dskvich/chatgpt-telegram-bot/pkg/domain"
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+)
+
+// IsImageEdit matches a drawing prompt sent with a photo or in reply to one. Other replies to
+// photos, generated ones included, are ordinary messages; free-form refinements go through
+// the refine button, see IsRefiningImage.
+func IsImageEdit() bot.MatchFunc {
+	return func(update *models.Update) bool {
+		if update.Message == nil {
+			return false
+		}
+		msg := update.Message
+
+		if len(msg.Photo) > 0 {
+			return domain.IsImagePrompt(msg.Caption)
+		}
+
+		reply := msg.ReplyToMessage
+		if reply == nil || len(reply.Photo) == 0 || msg.Text == "" {
+			return false
+		}
+
+		return domain.IsImagePrompt(msg.Text)
+	}
+}
@@ -0,0 +1,17 @@
+package matchers
+
+import (
+	"strings"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+)
+
+// IsKnowledgeBaseUpload matches a file sent with a /kb command in the caption. Captions are not
+// checked by the command handlers, which only look at the message text.
+func IsKnowledgeBaseUpload() bot.MatchFunc {
+	return func(update *models.Update) bool {
+		return update.Message != nil && update.Message.Document != nil &&
+			strings.HasPrefix(update.Message.Caption, "/kb")
+	}
+}
@@ -26,3 +26,20 @@ func IsEditingSystemPrompt(provider StateProvider) bot.MatchFunc {
 		return state == domain.StateEditSystemPrompt
 	}
 }
+
+func IsRefiningImage(provider StateProvider) bot.MatchFunc {
+	return func(update *models.Update) bool {
+		if update.Message == nil {
+			return false
+		}
+		chatID := update.Message.Chat.ID
+		topicID := update.Message.MessageThreadID
+
+		state, ok := provider.Get(chatID, topicID)
+		if !ok {
+			return false
+		}
+
+		return state == domain.StateRefineImage
+	}
+}
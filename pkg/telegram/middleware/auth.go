@@ -9,6 +9,8 @@ import (
 	"github.com/go-telegram/bot/models"
 )
 
+// Auth lets through updates from authorized users. Messages without a sender, such as posts
+// on behalf of a channel, are dropped, so the handlers can rely on Message.From.
 func Auth(authorizedIDs []int64) bot.Middleware {
 	return func(next bot.HandlerFunc) bot.HandlerFunc {
 		return func(ctx context.Context, b *bot.Bot, update *models.Update) {
@@ -17,6 +19,10 @@ func Auth(authorizedIDs []int64) bot.Middleware {
 			var userID int64
 			switch {
 			case update.Message != nil:
+				if update.Message.From == nil {
+					slog.WarnContext(ctx, "Received message without a sender", "chatID", update.Message.Chat.ID)
+					return
+				}
 				userID = update.Message.From.ID
 			case update.CallbackQuery != nil:
 				userID = update.CallbackQuery.From.ID
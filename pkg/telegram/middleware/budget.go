@@ -0,0 +1,164 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/dskvich/chatgpt-telegram-bot/pkg/domain"
+	"github.com/dskvich/chatgpt-telegram-bot/pkg/logger"
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"github.com/samber/lo"
+)
+
+type budgetProvider interface {
+	List(ctx context.Context, scope domain.BudgetScope, subjectID int64) ([]domain.Budget, error)
+}
+
+type spendingProvider interface {
+	TotalByUser(ctx context.Context, userID int64, since time.Time) (domain.Usage, error)
+	TotalByChat(ctx context.Context, chatID int64, since time.Time) (domain.Usage, error)
+}
+
+// billableCommands are the commands that spend money, e.g. embeddings of a document added to the knowledge base.
+var billableCommands = []string{"/kb add"}
+
+// billableCallbacks are the buttons that call a paid API.
+var billableCallbacks = []string{
+	domain.GenImageCallbackPrefix,
+	domain.SpeakCallbackPrefix,
+}
+
+// Budget refuses paid requests once the user or the chat has spent its daily or monthly budget.
+// Free commands and settings callbacks always pass, so budgets can be inspected and changed.
+func Budget(budgets budgetProvider, spending spendingProvider) bot.Middleware {
+	isBillable := func(update *models.Update) bool {
+		switch {
+		case update.Message != nil:
+			text := strings.Join(strings.Fields(lo.CoalesceOrEmpty(update.Message.Text, update.Message.Caption)), " ")
+			return !strings.HasPrefix(text, "/") || lo.SomeBy(billableCommands, func(command string) bool {
+				return strings.HasPrefix(text, command)
+			})
+		case update.CallbackQuery != nil:
+			return lo.SomeBy(billableCallbacks, func(prefix string) bool {
+				return strings.HasPrefix(update.CallbackQuery.Data, prefix)
+			})
+		default:
+			return false
+		}
+	}
+
+	spent := func(ctx context.Context, budget domain.Budget, since time.Time) (float64, error) {
+		var (
+			usage domain.Usage
+			err   error
+		)
+		if budget.Scope == domain.BudgetScopeChat {
+			usage, err = spending.TotalByChat(ctx, budget.SubjectID, since)
+		} else {
+			usage, err = spending.TotalByUser(ctx, budget.SubjectID, since)
+		}
+		return usage.Cost, err
+	}
+
+	exceeded := func(ctx context.Context, scope domain.BudgetScope, subjectID int64) (*domain.Budget, float64, error) {
+		list, err := budgets.List(ctx, scope, subjectID)
+		if err != nil {
+			return nil, 0, err
+		}
+
+		now := time.Now()
+		for _, budget := range list {
+			total, err := spent(ctx, budget, budget.Period.Start(now))
+			if err != nil {
+				return nil, 0, err
+			}
+			if total >= budget.Limit {
+				return &budget, total, nil
+			}
+		}
+
+		return nil, 0, nil
+	}
+
+	describe := func(budget *domain.Budget, total float64) string {
+		period := map[domain.BudgetPeriod]string{
+			domain.BudgetPeriodDay:   "дневной",
+			domain.BudgetPeriodMonth: "месячный",
+		}[budget.Period]
+		subject := map[domain.BudgetScope]string{
+			domain.BudgetScopeUser: "пользователя",
+			domain.BudgetScopeChat: "чата",
+		}[budget.Scope]
+		resetAt := budget.Period.End(time.Now())
+
+		return fmt.Sprintf("💸 Исчерпан %s бюджет %s: потрачено $%.2f из $%.2f.\nЗапросы снова будут доступны %s.",
+			period, subject, total, budget.Limit, resetAt.Format("02.01.2006 15:04"))
+	}
+
+	return func(next bot.HandlerFunc) bot.HandlerFunc {
+		return func(ctx context.Context, b *bot.Bot, update *models.Update) {
+			if !isBillable(update) {
+				next(ctx, b, update)
+				return
+			}
+
+			var (
+				userID  int64
+				chatID  int64
+				topicID int
+			)
+			switch {
+			case update.Message != nil:
+				userID, chatID, topicID = update.Message.From.ID, update.Message.Chat.ID, update.Message.MessageThreadID
+			case update.CallbackQuery.Message.Message != nil:
+				msg := update.CallbackQuery.Message.Message
+				userID, chatID, topicID = update.CallbackQuery.From.ID, msg.Chat.ID, msg.MessageThreadID
+			case update.CallbackQuery.Message.InaccessibleMessage != nil:
+				userID, chatID = update.CallbackQuery.From.ID, update.CallbackQuery.Message.InaccessibleMessage.Chat.ID
+			default:
+				next(ctx, b, update)
+				return
+			}
+
+			for _, subject := range []struct {
+				scope domain.BudgetScope
+				id    int64
+			}{
+				{domain.BudgetScopeUser, userID},
+				{domain.BudgetScopeChat, chatID},
+			} {
+				budget, total, err := exceeded(ctx, subject.scope, subject.id)
+				if err != nil {
+					slog.ErrorContext(ctx, "Failed to check budget", "scope", subject.scope, logger.Err(err))
+					continue
+				}
+				if budget == nil {
+					continue
+				}
+
+				slog.WarnContext(ctx, "Budget exceeded",
+					"scope", budget.Scope, "subjectID", budget.SubjectID, "period", budget.Period, "spent", total)
+
+				if update.CallbackQuery != nil {
+					b.AnswerCallbackQuery(ctx, &bot.AnswerCallbackQueryParams{
+						CallbackQueryID: update.CallbackQuery.ID,
+						ShowAlert:       false,
+					})
+				}
+
+				b.SendMessage(ctx, &bot.SendMessageParams{
+					ChatID:          chatID,
+					MessageThreadID: topicID,
+					Text:            describe(budget, total),
+				})
+				return
+			}
+
+			next(ctx, b, update)
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"slices"
+	"strings"
+
+	"github.com/dskvich/chatgpt-telegram-bot/pkg/domain"
+	"github.com/dskvich/chatgpt-telegram-bot/pkg/logger"
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"github.com/samber/lo"
+)
+
+type moderator interface {
+	Moderate(ctx context.Context, text string) (*domain.ModerationResult, error)
+}
+
+type moderationCategoriesProvider interface {
+	GetCategories(ctx context.Context, chatID int64) ([]string, error)
+}
+
+// Moderation checks user text before it reaches a model. Flagged prompts are blocked or passed
+// on with a warning according to the policy. A chat can narrow the categories the policy applies
+// to, flags in other categories are only logged. Moderation failures never block a request.
+// Only what the user typed or said is checked: text extracted from attached documents and the
+// answers of the models are not moderated.
+func Moderation(moderator moderator, categoriesProvider moderationCategoriesProvider, policy domain.ModerationPolicy) bot.Middleware {
+	const decisionAllow = "allow"
+
+	// decide returns the categories the policy applies to, logging the decision for every flagged one.
+	decide := func(ctx context.Context, chatID int64, result *domain.ModerationResult) []string {
+		blocked, err := categoriesProvider.GetCategories(ctx, chatID)
+		if err != nil && !errors.Is(err, domain.ErrNotFound) {
+			slog.ErrorContext(ctx, "Failed to get moderation categories", logger.Err(err))
+		}
+		chatDefault := err != nil
+
+		var triggered []string
+		for _, category := range domain.ModerationCategories {
+			if !result.Categories[category] {
+				continue
+			}
+
+			decision := decisionAllow
+			if chatDefault || slices.Contains(blocked, category) {
+				decision = string(policy)
+				triggered = append(triggered, category)
+			}
+
+			slog.InfoContext(ctx, "Moderation decision",
+				"category", category, "score", result.Scores[category], "decision", decision)
+		}
+
+		return triggered
+	}
+
+	return func(next bot.HandlerFunc) bot.HandlerFunc {
+		return func(ctx context.Context, b *bot.Bot, update *models.Update) {
+			if policy == domain.ModerationPolicyOff || update.Message == nil {
+				next(ctx, b, update)
+				return
+			}
+
+			text := lo.CoalesceOrEmpty(update.Message.Text, update.Message.Caption)
+			if text == "" || strings.HasPrefix(text, "/") {
+				next(ctx, b, update)
+				return
+			}
+
+			result, err := moderator.Moderate(ctx, text)
+			if err != nil {
+				slog.ErrorContext(ctx, "Failed to moderate message", logger.Err(err))
+				next(ctx, b, update)
+				return
+			}
+
+			if !result.Flagged {
+				next(ctx, b, update)
+				return
+			}
+
+			triggered := decide(ctx, update.Message.Chat.ID, result)
+			if len(triggered) == 0 {
+				next(ctx, b, update)
+				return
+			}
+
+			if policy == domain.ModerationPolicyBlock {
+				b.SendMessage(ctx, &bot.SendMessageParams{
+					ChatID:          update.Message.Chat.ID,
+					MessageThreadID: update.Message.MessageThreadID,
+					Text:            "🚫 Сообщение заблокировано модерацией: " + strings.Join(triggered, ", "),
+				})
+				return
+			}
+
+			b.SendMessage(ctx, &bot.SendMessageParams{
+				ChatID:          update.Message.Chat.ID,
+				MessageThreadID: update.Message.MessageThreadID,
+				Text:            "⚠️ Сообщение нарушает правила использования: " + strings.Join(triggered, ", "),
+			})
+
+			next(ctx, b, update)
+		}
+	}
+}
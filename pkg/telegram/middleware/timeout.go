@@ -0,0 +1,27 @@
+package middleware
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+)
+
+// Timeout gives every update a deadline, so that a hung request cannot hold a worker forever.
+// Zero turns the deadline off.
+func Timeout(timeout time.Duration) bot.Middleware {
+	return func(next bot.HandlerFunc) bot.HandlerFunc {
+		return func(ctx context.Context, b *bot.Bot, update *models.Update) {
+			if timeout <= 0 {
+				next(ctx, b, update)
+				return
+			}
+
+			ctx, cancel := context.WithTimeout(ctx, timeout)
+			defer cancel()
+
+			next(ctx, b, update)
+		}
+	}
+}
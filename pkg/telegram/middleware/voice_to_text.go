@@ -2,17 +2,21 @@ package middleware
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"log/slog"
-	"net/http"
-	"net/url"
 	"os"
 	"path/filepath"
-	"time"
+	"strings"
+	"unicode/utf8"
 
+	"github.com/dskvich/chatgpt-telegram-bot/pkg/domain"
+	"github.com/dskvich/chatgpt-telegram-bot/pkg/logger"
+	"github.com/dskvich/chatgpt-telegram-bot/pkg/telegram/errtext"
 	"github.com/go-telegram/bot"
 	"github.com/go-telegram/bot/models"
+	"github.com/samber/lo"
 )
 
 type audioConverter interface {
@@ -20,107 +24,275 @@ type audioConverter interface {
 }
 
 type audioTranscriber interface {
-	TranscribeAudio(ctx context.Context, audioFilePath string) (string, error)
+	TranscribeAudio(ctx context.Context, audio io.Reader, fileName string) (domain.Transcript, error)
+	TranslateAudio(ctx context.Context, audio io.Reader, fileName string) (domain.Transcript, error)
 }
 
-func VoiceToText(converter audioConverter, transcriber audioTranscriber) bot.Middleware {
+type longTranscriber interface {
+	Transcribe(ctx context.Context, path string, translate bool, onProgress func(done, total int)) (domain.Transcript, error)
+}
+
+type mediaDownloader interface {
+	Open(ctx context.Context, b *bot.Bot, fileID string) (io.ReadCloser, error)
+}
+
+type voiceToTextSettingsProvider interface {
+	Get(ctx context.Context, chatID int64, topicID int) (*domain.Settings, error)
+}
+
+type transcriptionUsageSaver interface {
+	Save(ctx context.Context, record domain.UsageRecord) error
+}
+
+const (
+	// maxMediaFileSize is the largest file the Bot API lets bots download.
+	maxMediaFileSize = 20 << 20
+	// Longer recordings are transcribed in segments.
+	longRecordingSeconds = 10 * 60
+
+	// maxInlineTranscriptLen keeps the transcript in a single message, longer ones are sent as a file.
+	maxInlineTranscriptLen = 3500
+	defaultSummaryQuestion = "Кратко перескажи эту запись: главные темы, решения и договоренности."
+)
+
+// whisperFormats are the file extensions the transcription endpoint accepts as is.
+var whisperFormats = []string{".flac", ".m4a", ".mp3", ".mp4", ".mpeg", ".mpga", ".oga", ".ogg", ".wav", ".webm"}
+
+// mimeExtensions names files that come without an extension by their MIME type.
+var mimeExtensions = map[string]string{
+	"audio/ogg":   ".ogg",
+	"audio/mpeg":  ".mp3",
+	"audio/mp4":   ".m4a",
+	"audio/x-m4a": ".m4a",
+	"audio/wav":   ".wav",
+	"audio/x-wav": ".wav",
+	"audio/flac":  ".flac",
+	"audio/webm":  ".webm",
+	"video/mp4":   ".mp4",
+}
+
+// media is a message attachment with an audio track to transcribe.
+type media struct {
+	fileID   string
+	fileName string
+	mimeType string
+	fileSize int64
+	duration int // seconds, zero when Telegram does not report it
+	icon     string
+}
+
+func mediaOf(msg *models.Message) (*media, bool) {
+	switch {
+	case msg.Voice != nil:
+		return &media{msg.Voice.FileID, "voice.ogg", msg.Voice.MimeType, msg.Voice.FileSize, msg.Voice.Duration, "🎤"}, true
+	case msg.Audio != nil:
+		return &media{msg.Audio.FileID, msg.Audio.FileName, msg.Audio.MimeType, msg.Audio.FileSize, msg.Audio.Duration, "🎧"}, true
+	case msg.VideoNote != nil:
+		return &media{msg.VideoNote.FileID, "video_note.mp4", "video/mp4", int64(msg.VideoNote.FileSize), msg.VideoNote.Duration, "🎬"}, true
+	case msg.Video != nil:
+		return &media{msg.Video.FileID, msg.Video.FileName, msg.Video.MimeType, msg.Video.FileSize, msg.Video.Duration, "🎬"}, true
+	case msg.Document != nil && strings.HasPrefix(msg.Document.MimeType, "audio/"):
+		return &media{msg.Document.FileID, msg.Document.FileName, msg.Document.MimeType, msg.Document.FileSize, 0, "🎧"}, true
+	default:
+		return nil, false
+	}
+}
+
+// uploadName returns the file name to send the media under, with an extension that tells the
+// transcription endpoint the format, and false when the format has to be converted first.
+func (m *media) uploadName() (string, bool) {
+	name := m.fileName
+	if filepath.Ext(name) == "" {
+		name = lo.CoalesceOrEmpty(name, "audio") + mimeExtensions[strings.ToLower(m.mimeType)]
+	}
+	return name, lo.Contains(whisperFormats, strings.ToLower(filepath.Ext(name)))
+}
+
+// VoiceToText transcribes voice notes, audio files, video notes, videos and audio documents,
+// and passes the transcript on as the message text. A caption is kept in front of the transcript.
+// Long recordings are transcribed in segments with the progress shown in a status message, and a
+// transcript too long for a message is sent as a file and summarized instead of being answered.
+// Chats with voice translation on get the speech translated into English instead.
+func VoiceToText(
+	converter audioConverter,
+	transcriber audioTranscriber,
+	longTranscriber longTranscriber,
+	settingsProvider voiceToTextSettingsProvider,
+	downloader mediaDownloader,
+	usageSaver transcriptionUsageSaver,
+) bot.Middleware {
 	return func(next bot.HandlerFunc) bot.HandlerFunc {
-		downloadFileToBuffer := func(link string) ([]byte, error) {
-			resp, err := http.Get(link)
-			if err != nil {
-				return nil, err
-			}
-			defer resp.Body.Close()
+		saveTempMediaFile := func(data io.Reader, fileName string) (string, error) {
+			const voiceTempDir = "tmp/voices"
 
-			if resp.StatusCode != http.StatusOK {
-				return nil, err
+			if err := os.MkdirAll(voiceTempDir, os.ModePerm); err != nil {
+				return "", fmt.Errorf("unable to create temp directory: %w", err)
 			}
 
-			data, err := io.ReadAll(resp.Body)
+			file, err := os.CreateTemp(voiceTempDir, "media-*"+filepath.Ext(fileName))
 			if err != nil {
-				return nil, err
+				return "", fmt.Errorf("unable to create media file: %w", err)
 			}
-			return data, nil
+			defer file.Close()
+
+			if _, err := io.Copy(file, data); err != nil {
+				os.Remove(file.Name())
+				return "", fmt.Errorf("unable to write media file: %w", err)
+			}
+
+			return file.Name(), nil
 		}
 
-		saveTempVoiceFile := func(data []byte) (string, error) {
-			const (
-				voiceTempDir      = "tmp/voices"
-				voiceTempFilePerm = 0o644
-			)
+		transcribeLong := func(ctx context.Context, b *bot.Bot, msg *models.Message, path string, translate bool) (domain.Transcript, error) {
+			var status *models.Message
 
-			if err := os.MkdirAll(voiceTempDir, os.ModePerm); err != nil {
-				return "", fmt.Errorf("unable to create temp directory: %w", err)
-			}
+			transcript, err := longTranscriber.Transcribe(ctx, path, translate, func(done, total int) {
+				text := fmt.Sprintf("⏳ Распознаю длинную запись: %d из %d фрагментов", done, total)
+
+				if status == nil {
+					var err error
+					status, err = b.SendMessage(ctx, &bot.SendMessageParams{
+						ChatID:          msg.Chat.ID,
+						MessageThreadID: msg.MessageThreadID,
+						Text:            text,
+					})
+					if err != nil {
+						slog.ErrorContext(ctx, "Failed to send transcription progress", logger.Err(err))
+					}
+					return
+				}
 
-			voiceFilePath := filepath.Join(voiceTempDir, fmt.Sprintf("voice-%d.ogg", time.Now().UnixNano()))
-			if err := os.WriteFile(voiceFilePath, data, voiceTempFilePerm); err != nil {
-				return "", fmt.Errorf("unable to write voice file: %w", err)
+				if _, err := b.EditMessageText(ctx, &bot.EditMessageTextParams{
+					ChatID:    msg.Chat.ID,
+					MessageID: status.ID,
+					Text:      text,
+				}); err != nil {
+					slog.ErrorContext(ctx, "Failed to update transcription progress", logger.Err(err))
+				}
+			})
+
+			if status != nil {
+				b.DeleteMessage(ctx, &bot.DeleteMessageParams{ChatID: msg.Chat.ID, MessageID: status.ID})
 			}
 
-			return voiceFilePath, nil
+			return transcript, err
 		}
 
-		processVoiceMessage := func(ctx context.Context, b *bot.Bot, update *models.Update) (string, error) {
-			voiceFile, err := b.GetFile(ctx, &bot.GetFileParams{FileID: update.Message.Voice.FileID})
-			if err != nil {
-				return "", fmt.Errorf("unable to get voice file metadata: %w", err)
+		// processMedia streams the download straight to the transcription endpoint. Only formats the
+		// endpoint does not accept and long recordings go through a temporary file and ffmpeg.
+		processMedia := func(ctx context.Context, b *bot.Bot, msg *models.Message, m *media, translate bool) (domain.Transcript, error) {
+			if m.fileSize > maxMediaFileSize {
+				return domain.Transcript{}, errors.New("file is larger than 20 MB")
 			}
 
-			voiceFileURL, err := url.Parse(b.FileDownloadLink(voiceFile))
+			mediaData, err := downloader.Open(ctx, b, m.fileID)
 			if err != nil {
-				return "", fmt.Errorf("invalid voice file URL: %w", err)
+				return domain.Transcript{}, err
 			}
+			defer mediaData.Close()
 
-			voiceBytes, err := downloadFileToBuffer(voiceFileURL.String())
-			if err != nil {
-				return "", fmt.Errorf("unable to download voice file: %w", err)
+			recognize := lo.Ternary(translate, transcriber.TranslateAudio, transcriber.TranscribeAudio)
+
+			long := m.duration > longRecordingSeconds
+
+			uploadName, supported := m.uploadName()
+			if supported && !long {
+				return recognize(ctx, mediaData, uploadName)
 			}
 
-			voiceFilePath, err := saveTempVoiceFile(voiceBytes)
+			mediaFilePath, err := saveTempMediaFile(mediaData, m.fileName)
 			if err != nil {
-				return "", fmt.Errorf("unable to save temporary voice file: %w", err)
+				return domain.Transcript{}, fmt.Errorf("unable to save temporary media file: %w", err)
+			}
+			defer os.Remove(mediaFilePath)
+
+			if long {
+				slog.InfoContext(ctx, "Transcribing long recording in segments", "duration", m.duration, "fileSize", m.fileSize)
+				return transcribeLong(ctx, b, msg, mediaFilePath, translate)
 			}
-			defer os.Remove(voiceFilePath)
 
-			mp3Path, err := converter.ConvertToMP3(ctx, voiceFilePath)
+			slog.InfoContext(ctx, "Converting media the transcription endpoint does not accept",
+				"fileName", m.fileName, "mimeType", m.mimeType)
+
+			mp3Path, err := converter.ConvertToMP3(ctx, mediaFilePath)
 			if err != nil {
-				return "", fmt.Errorf("unable to convert voice file to MP3: %w", err)
+				return domain.Transcript{}, fmt.Errorf("unable to convert media file to MP3: %w", err)
 			}
 			defer os.Remove(mp3Path)
 
-			transcribedText, err := transcriber.TranscribeAudio(ctx, mp3Path)
+			mp3File, err := os.Open(mp3Path)
 			if err != nil {
-				return "", fmt.Errorf("unable to transcribe MP3 file: %w", err)
+				return domain.Transcript{}, fmt.Errorf("unable to open MP3 file: %w", err)
 			}
+			defer mp3File.Close()
 
-			return transcribedText, nil
+			return recognize(ctx, mp3File, filepath.Base(mp3Path))
 		}
 
 		return func(ctx context.Context, b *bot.Bot, update *models.Update) {
 			slog.InfoContext(ctx, "Voice to text middleware started")
 
-			if update.Message == nil || update.Message.Voice == nil {
+			if update.Message == nil {
 				next(ctx, b, update)
 				return
 			}
 
-			transcribedText, err := processVoiceMessage(ctx, b, update)
+			m, ok := mediaOf(update.Message)
+			if !ok {
+				next(ctx, b, update)
+				return
+			}
+
+			settings, err := settingsProvider.Get(ctx, update.Message.Chat.ID, update.Message.MessageThreadID)
+			if err != nil && !errors.Is(err, domain.ErrNotFound) {
+				slog.ErrorContext(ctx, "Failed to get settings, transcribing", logger.Err(err))
+			}
+			translate := settings != nil && settings.VoiceTranslation
+
+			transcript, err := processMedia(ctx, b, update.Message, m, translate)
 			if err != nil {
 				b.SendMessage(ctx, &bot.SendMessageParams{
 					ChatID:          update.Message.Chat.ID,
 					MessageThreadID: update.Message.MessageThreadID,
-					Text:            fmt.Sprintf("❌ Ошибка при обработке голосового сообщения: %s", err),
+					Text:            errtext.Format(ctx, "Не удалось распознать речь", err),
 				})
 				return
 			}
 
-			update.Message.Text = transcribedText
+			if err := usageSaver.Save(ctx, domain.UsageRecord{
+				UserID:  update.Message.From.ID,
+				ChatID:  update.Message.Chat.ID,
+				TopicID: update.Message.MessageThreadID,
+				Model:   domain.Whisper1Model,
+				Usage:   domain.Usage{Transcriptions: 1, AudioSeconds: lo.CoalesceOrEmpty(transcript.Duration, m.duration)},
+			}); err != nil {
+				slog.ErrorContext(ctx, "Failed to save usage", logger.Err(err))
+			}
+
+			update.Message.Document = nil // the transcript replaces the file
+
+			if utf8.RuneCountInString(transcript.Text) > maxInlineTranscriptLen {
+				if _, err := b.SendDocument(ctx, &bot.SendDocumentParams{
+					ChatID:          update.Message.Chat.ID,
+					MessageThreadID: update.Message.MessageThreadID,
+					Document:        &models.InputFileUpload{Filename: "transcript.txt", Data: strings.NewReader(transcript.Text)},
+					Caption:         m.icon + " Полный текст записи",
+				}); err != nil {
+					slog.ErrorContext(ctx, "Failed to send transcript file", logger.Err(err))
+				}
+
+				// The caption is the question about the recording, like for documents.
+				update.Message.Text = lo.CoalesceOrEmpty(update.Message.Caption, defaultSummaryQuestion) + "\n\n" + transcript.Text
+				next(ctx, b, update)
+				return
+			}
+
+			update.Message.Text = strings.TrimSpace(update.Message.Caption + "\n\n" + transcript.Text)
 
 			b.SendMessage(ctx, &bot.SendMessageParams{
 				ChatID:          update.Message.Chat.ID,
 				MessageThreadID: update.Message.MessageThreadID,
-				Text:            fmt.Sprintf("🎤 %s", transcribedText),
+				Text:            fmt.Sprintf("%s %s", m.icon, transcript.Text),
 			})
 
 			next(ctx, b, update)
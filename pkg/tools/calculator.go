@@ -0,0 +1,276 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/dskvich/chatgpt-telegram-bot/pkg/domain"
+)
+
+// Calculator evaluates arithmetic expressions with + - * / % ^, parentheses, the constants pi and e
+// and the functions sqrt, abs, ln, log10, sin, cos, tan, round, floor and ceil.
+type Calculator struct{}
+
+func (Calculator) Definition() domain.ToolDefinition {
+	return domain.ToolDefinition{
+		Name:        "calculator",
+		Description: "Evaluates an arithmetic expression exactly. Use it for any non-trivial calculation.",
+		Parameters: json.RawMessage(`{
+			"type": "object",
+			"properties": {
+				"expression": {
+					"type": "string",
+					"description": "Expression such as \"(2 + 3) * 4 ^ 2 / sqrt(16)\". Supports + - * / % ^, pi, e, sqrt, abs, ln, log10, sin, cos, tan, round, floor, ceil."
+				}
+			},
+			"required": ["expression"],
+			"additionalProperties": false
+		}`),
+	}
+}
+
+func (Calculator) Call(_ context.Context, arguments json.RawMessage) (string, error) {
+	var args struct {
+		Expression string `json:"expression"`
+	}
+	if err := json.Unmarshal(arguments, &args); err != nil {
+		return "", fmt.Errorf("parsing arguments: %w", err)
+	}
+
+	p := &exprParser{input: args.Expression}
+	value, err := p.parse()
+	if err != nil {
+		return "", err
+	}
+
+	if math.IsNaN(value) || math.IsInf(value, 0) {
+		return "", errors.New("result is not a finite number")
+	}
+
+	return strconv.FormatFloat(value, 'g', -1, 64), nil
+}
+
+// exprParser is a recursive descent parser over the grammar:
+//
+//	expr   = term { ("+" | "-") term }
+//	term   = unary { ("*" | "/" | "%") unary }
+//	unary  = ("+" | "-") unary | power
+//	power  = atom [ "^" unary ]
+//	atom   = number | ident [ "(" expr ")" ] | "(" expr ")"
+type exprParser struct {
+	input string
+	pos   int
+}
+
+func (p *exprParser) parse() (float64, error) {
+	v, err := p.expr()
+	if err != nil {
+		return 0, err
+	}
+	p.skipSpaces()
+	if p.pos < len(p.input) {
+		return 0, fmt.Errorf("unexpected %q at position %d", p.input[p.pos], p.pos)
+	}
+	return v, nil
+}
+
+func (p *exprParser) expr() (float64, error) {
+	v, err := p.term()
+	if err != nil {
+		return 0, err
+	}
+	for {
+		switch p.peek() {
+		case '+':
+			p.pos++
+			r, err := p.term()
+			if err != nil {
+				return 0, err
+			}
+			v += r
+		case '-':
+			p.pos++
+			r, err := p.term()
+			if err != nil {
+				return 0, err
+			}
+			v -= r
+		default:
+			return v, nil
+		}
+	}
+}
+
+func (p *exprParser) term() (float64, error) {
+	v, err := p.unary()
+	if err != nil {
+		return 0, err
+	}
+	for {
+		op := p.peek()
+		if op != '*' && op != '/' && op != '%' {
+			return v, nil
+		}
+		p.pos++
+		r, err := p.unary()
+		if err != nil {
+			return 0, err
+		}
+		switch op {
+		case '*':
+			v *= r
+		case '/':
+			if r == 0 {
+				return 0, errors.New("division by zero")
+			}
+			v /= r
+		case '%':
+			if r == 0 {
+				return 0, errors.New("division by zero")
+			}
+			v = math.Mod(v, r)
+		}
+	}
+}
+
+func (p *exprParser) unary() (float64, error) {
+	switch p.peek() {
+	case '+':
+		p.pos++
+		return p.unary()
+	case '-':
+		p.pos++
+		v, err := p.unary()
+		return -v, err
+	default:
+		return p.power()
+	}
+}
+
+func (p *exprParser) power() (float64, error) {
+	base, err := p.atom()
+	if err != nil {
+		return 0, err
+	}
+	if p.peek() != '^' {
+		return base, nil
+	}
+	p.pos++
+	exp, err := p.unary() // right associative
+	if err != nil {
+		return 0, err
+	}
+	return math.Pow(base, exp), nil
+}
+
+func (p *exprParser) atom() (float64, error) {
+	c := p.peek()
+	switch {
+	case c == '(':
+		p.pos++
+		v, err := p.expr()
+		if err != nil {
+			return 0, err
+		}
+		if p.peek() != ')' {
+			return 0, errors.New("missing closing parenthesis")
+		}
+		p.pos++
+		return v, nil
+	case c == '.' || unicode.IsDigit(rune(c)):
+		return p.number()
+	case unicode.IsLetter(rune(c)):
+		return p.ident()
+	case c == 0:
+		return 0, errors.New("unexpected end of expression")
+	default:
+		return 0, fmt.Errorf("unexpected %q at position %d", c, p.pos)
+	}
+}
+
+func (p *exprParser) number() (float64, error) {
+	start := p.pos
+	for p.pos < len(p.input) && (p.input[p.pos] == '.' || unicode.IsDigit(rune(p.input[p.pos]))) {
+		p.pos++
+	}
+	// exponent, e.g. 1e-3
+	if p.pos < len(p.input) && (p.input[p.pos] == 'e' || p.input[p.pos] == 'E') {
+		next := p.pos + 1
+		if next < len(p.input) && (p.input[next] == '+' || p.input[next] == '-') {
+			next++
+		}
+		if next < len(p.input) && unicode.IsDigit(rune(p.input[next])) {
+			p.pos = next
+			for p.pos < len(p.input) && unicode.IsDigit(rune(p.input[p.pos])) {
+				p.pos++
+			}
+		}
+	}
+
+	v, err := strconv.ParseFloat(p.input[start:p.pos], 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid number %q", p.input[start:p.pos])
+	}
+	return v, nil
+}
+
+func (p *exprParser) ident() (float64, error) {
+	start := p.pos
+	for p.pos < len(p.input) && (unicode.IsLetter(rune(p.input[p.pos])) || unicode.IsDigit(rune(p.input[p.pos]))) {
+		p.pos++
+	}
+	name := strings.ToLower(p.input[start:p.pos])
+
+	switch name {
+	case "pi":
+		return math.Pi, nil
+	case "e":
+		return math.E, nil
+	}
+
+	fn, ok := map[string]func(float64) float64{
+		"sqrt":  math.Sqrt,
+		"abs":   math.Abs,
+		"ln":    math.Log,
+		"log10": math.Log10,
+		"sin":   math.Sin,
+		"cos":   math.Cos,
+		"tan":   math.Tan,
+		"round": math.Round,
+		"floor": math.Floor,
+		"ceil":  math.Ceil,
+	}[name]
+	if !ok {
+		return 0, fmt.Errorf("unknown identifier %q", name)
+	}
+
+	if p.peek() != '(' {
+		return 0, fmt.Errorf("expected '(' after %s", name)
+	}
+	arg, err := p.atom()
+	if err != nil {
+		return 0, err
+	}
+	return fn(arg), nil
+}
+
+// peek skips whitespace and returns the next byte, or 0 at the end of input.
+func (p *exprParser) peek() byte {
+	p.skipSpaces()
+	if p.pos >= len(p.input) {
+		return 0
+	}
+	return p.input[p.pos]
+}
+
+func (p *exprParser) skipSpaces() {
+	for p.pos < len(p.input) && unicode.IsSpace(rune(p.input[p.pos])) {
+		p.pos++
+	}
+}
@@ -0,0 +1,51 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+	_ "time/tzdata" // the runtime image has no zoneinfo
+
+	"github.com/dskvich/chatgpt-telegram-bot/pkg/domain"
+)
+
+// Clock tells the current date and time in an IANA time zone.
+type Clock struct{}
+
+func (Clock) Definition() domain.ToolDefinition {
+	return domain.ToolDefinition{
+		Name:        "current_time",
+		Description: "Returns the current date, time and weekday in the given time zone.",
+		Parameters: json.RawMessage(`{
+			"type": "object",
+			"properties": {
+				"timezone": {
+					"type": "string",
+					"description": "IANA time zone name such as \"Europe/Moscow\" or \"America/New_York\". Defaults to UTC."
+				}
+			},
+			"additionalProperties": false
+		}`),
+	}
+}
+
+func (Clock) Call(_ context.Context, arguments json.RawMessage) (string, error) {
+	var args struct {
+		Timezone string `json:"timezone"`
+	}
+	if err := json.Unmarshal(arguments, &args); err != nil {
+		return "", fmt.Errorf("parsing arguments: %w", err)
+	}
+
+	loc := time.UTC
+	if args.Timezone != "" {
+		var err error
+		if loc, err = time.LoadLocation(args.Timezone); err != nil {
+			return "", fmt.Errorf("unknown time zone %q", args.Timezone)
+		}
+	}
+
+	now := time.Now().In(loc)
+	return fmt.Sprintf("%s (%s, %s)", now.Format(time.RFC3339), now.Weekday(), loc), nil
+}
@@ -0,0 +1,52 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/dskvich/chatgpt-telegram-bot/pkg/domain"
+)
+
+// Tool is a local function the model may call.
+type Tool interface {
+	Definition() domain.ToolDefinition
+	Call(ctx context.Context, arguments json.RawMessage) (string, error)
+}
+
+type registry struct {
+	tools map[string]Tool
+	defs  []domain.ToolDefinition
+}
+
+func NewRegistry(tools ...Tool) *registry {
+	r := &registry{tools: make(map[string]Tool, len(tools))}
+	for _, t := range tools {
+		def := t.Definition()
+		r.tools[def.Name] = t
+		r.defs = append(r.defs, def)
+	}
+	return r
+}
+
+// Definitions returns the tools in the form they are announced to the model.
+func (r *registry) Definitions() []domain.ToolDefinition {
+	return r.defs
+}
+
+// Call runs the named tool with JSON encoded arguments.
+func (r *registry) Call(ctx context.Context, name, arguments string) (string, error) {
+	t, ok := r.tools[name]
+	if !ok {
+		return "", fmt.Errorf("unknown tool %q", name)
+	}
+
+	if arguments == "" {
+		arguments = "{}"
+	}
+	if !json.Valid([]byte(arguments)) {
+		return "", fmt.Errorf("arguments of tool %q are not valid JSON", name)
+	}
+
+	return t.Call(ctx, json.RawMessage(arguments))
+}
@@ -0,0 +1,120 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/dskvich/chatgpt-telegram-bot/pkg/domain"
+)
+
+// unitFactors maps a unit to its dimension and the factor to the dimension base unit.
+var unitFactors = map[string]struct {
+	dimension string
+	factor    float64
+}{
+	// length, base unit meter
+	"mm": {"length", 0.001}, "cm": {"length", 0.01}, "m": {"length", 1}, "km": {"length", 1000},
+	"in": {"length", 0.0254}, "ft": {"length", 0.3048}, "yd": {"length", 0.9144}, "mi": {"length", 1609.344},
+	"nmi": {"length", 1852},
+	// mass, base unit kilogram
+	"mg": {"mass", 1e-6}, "g": {"mass", 0.001}, "kg": {"mass", 1}, "t": {"mass", 1000},
+	"oz": {"mass", 0.028349523125}, "lb": {"mass", 0.45359237},
+	// volume, base unit liter
+	"ml": {"volume", 0.001}, "l": {"volume", 1}, "m3": {"volume", 1000},
+	"tsp": {"volume", 0.00492892159375}, "tbsp": {"volume", 0.01478676478125}, "cup": {"volume", 0.2365882365},
+	"floz": {"volume", 0.0295735295625}, "pt": {"volume", 0.473176473}, "qt": {"volume", 0.946352946},
+	"gal": {"volume", 3.785411784},
+	// speed, base unit meter per second
+	"m/s": {"speed", 1}, "km/h": {"speed", 1 / 3.6}, "mph": {"speed", 0.44704}, "kn": {"speed", 1852.0 / 3600},
+	// area, base unit square meter
+	"m2": {"area", 1}, "km2": {"area", 1e6}, "ha": {"area", 1e4}, "ft2": {"area", 0.09290304}, "acre": {"area", 4046.8564224},
+}
+
+// UnitConverter converts between units of length, mass, volume, speed, area and temperature.
+type UnitConverter struct{}
+
+func (UnitConverter) Definition() domain.ToolDefinition {
+	units := make([]string, 0, len(unitFactors)+3)
+	for u := range unitFactors {
+		units = append(units, u)
+	}
+	slices.Sort(units)
+	units = append(units, "C", "F", "K")
+
+	return domain.ToolDefinition{
+		Name:        "convert_units",
+		Description: "Converts a value between units of the same dimension: length, mass, volume, speed, area or temperature.",
+		Parameters: json.RawMessage(fmt.Sprintf(`{
+			"type": "object",
+			"properties": {
+				"value": {"type": "number"},
+				"from": {"type": "string", "description": "Source unit, one of: %[1]s"},
+				"to": {"type": "string", "description": "Target unit, one of: %[1]s"}
+			},
+			"required": ["value", "from", "to"],
+			"additionalProperties": false
+		}`, strings.Join(units, ", "))),
+	}
+}
+
+func (UnitConverter) Call(_ context.Context, arguments json.RawMessage) (string, error) {
+	var args struct {
+		Value float64 `json:"value"`
+		From  string  `json:"from"`
+		To    string  `json:"to"`
+	}
+	if err := json.Unmarshal(arguments, &args); err != nil {
+		return "", fmt.Errorf("parsing arguments: %w", err)
+	}
+
+	if v, ok := convertTemperature(args.Value, args.From, args.To); ok {
+		return formatConversion(args.Value, args.From, v, args.To), nil
+	}
+
+	from, ok := unitFactors[strings.ToLower(args.From)]
+	if !ok {
+		return "", fmt.Errorf("unknown unit %q", args.From)
+	}
+	to, ok := unitFactors[strings.ToLower(args.To)]
+	if !ok {
+		return "", fmt.Errorf("unknown unit %q", args.To)
+	}
+	if from.dimension != to.dimension {
+		return "", fmt.Errorf("cannot convert %s (%s) to %s (%s)", args.From, from.dimension, args.To, to.dimension)
+	}
+
+	return formatConversion(args.Value, args.From, args.Value*from.factor/to.factor, args.To), nil
+}
+
+func convertTemperature(value float64, from, to string) (float64, bool) {
+	toKelvin := map[string]func(float64) float64{
+		"C": func(v float64) float64 { return v + 273.15 },
+		"F": func(v float64) float64 { return (v-32)*5/9 + 273.15 },
+		"K": func(v float64) float64 { return v },
+	}
+	fromKelvin := map[string]func(float64) float64{
+		"C": func(v float64) float64 { return v - 273.15 },
+		"F": func(v float64) float64 { return (v-273.15)*9/5 + 32 },
+		"K": func(v float64) float64 { return v },
+	}
+
+	in, ok := toKelvin[strings.ToUpper(from)]
+	if !ok {
+		return 0, false
+	}
+	out, ok := fromKelvin[strings.ToUpper(to)]
+	if !ok {
+		return 0, false
+	}
+
+	return out(in(value)), true
+}
+
+func formatConversion(value float64, from string, result float64, to string) string {
+	return strconv.FormatFloat(value, 'g', -1, 64) + " " + from + " = " +
+		strconv.FormatFloat(result, 'g', 10, 64) + " " + to
+}
@@ -0,0 +1,176 @@
+package transcription
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/dskvich/chatgpt-telegram-bot/pkg/domain"
+)
+
+const (
+	// SegmentSeconds is the length of a segment; recordings longer than that are transcribed in parts.
+	SegmentSeconds = 10 * 60
+	overlapSeconds = 5
+
+	maxConcurrentSegments = 3
+
+	// maxOverlapWords bounds the search for the words repeated at the start of the next segment.
+	maxOverlapWords = 40
+	minOverlapWords = 2
+	maxOverlapShift = 3
+)
+
+type splitter interface {
+	SplitAudio(ctx context.Context, inputPath string, segmentSeconds, overlapSeconds int) ([]string, error)
+}
+
+type recognizer interface {
+	TranscribeAudio(ctx context.Context, audio io.Reader, fileName string) (domain.Transcript, error)
+	TranslateAudio(ctx context.Context, audio io.Reader, fileName string) (domain.Transcript, error)
+}
+
+// chunked transcribes recordings too long for a single request: the audio is split into
+// overlapping segments, which are recognized in parallel and stitched back together in order.
+type chunked struct {
+	splitter   splitter
+	recognizer recognizer
+}
+
+func NewChunked(splitter splitter, recognizer recognizer) *chunked {
+	return &chunked{splitter: splitter, recognizer: recognizer}
+}
+
+// Transcribe recognizes the recording at the path, translating it into English when asked.
+// onProgress is called with the number of recognized segments after every segment, never concurrently.
+// The duration of the transcript counts the overlaps, as they are billed too.
+func (c *chunked) Transcribe(
+	ctx context.Context,
+	path string,
+	translate bool,
+	onProgress func(done, total int),
+) (domain.Transcript, error) {
+	segments, err := c.splitter.SplitAudio(ctx, path, SegmentSeconds, overlapSeconds)
+	if err != nil {
+		return domain.Transcript{}, fmt.Errorf("splitting audio: %w", err)
+	}
+	defer func() {
+		for _, segment := range segments {
+			os.Remove(segment)
+		}
+	}()
+
+	onProgress(0, len(segments))
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		done    int
+		failure error
+		results = make([]domain.Transcript, len(segments))
+		sem     = make(chan struct{}, maxConcurrentSegments)
+	)
+
+	for i, segment := range segments {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			select {
+			case sem <- struct{}{}:
+				defer func() { <-sem }()
+			case <-ctx.Done():
+				return
+			}
+
+			transcript, err := c.recognize(ctx, segment, translate)
+
+			mu.Lock()
+			defer mu.Unlock()
+
+			if err != nil {
+				if failure == nil {
+					failure = fmt.Errorf("segment %d: %w", i+1, err)
+					cancel() // the transcript would have a gap, the other segments are not worth paying for
+				}
+				return
+			}
+
+			results[i] = transcript
+			done++
+			onProgress(done, len(segments))
+		}()
+	}
+	wg.Wait()
+
+	if failure != nil {
+		return domain.Transcript{}, failure
+	}
+	if err := ctx.Err(); err != nil {
+		return domain.Transcript{}, err
+	}
+
+	var res domain.Transcript
+	for _, transcript := range results {
+		res.Text = stitch(res.Text, transcript.Text)
+		res.Duration += transcript.Duration
+	}
+
+	slog.InfoContext(ctx, "Transcribed recording in segments", "segments", len(segments), "seconds", res.Duration)
+
+	return res, nil
+}
+
+func (c *chunked) recognize(ctx context.Context, segment string, translate bool) (domain.Transcript, error) {
+	file, err := os.Open(segment)
+	if err != nil {
+		return domain.Transcript{}, fmt.Errorf("opening segment: %w", err)
+	}
+	defer file.Close()
+
+	if translate {
+		return c.recognizer.TranslateAudio(ctx, file, filepath.Base(segment))
+	}
+	return c.recognizer.TranscribeAudio(ctx, file, filepath.Base(segment))
+}
+
+// stitch appends the next segment to the transcript, dropping the words of the overlap that the
+// transcript already ends with. The next segment may start with a few words cut in the middle,
+// so the repeated words are looked for slightly after its start too.
+func stitch(text, next string) string {
+	words, nextWords := strings.Fields(text), strings.Fields(next)
+
+	for n := min(maxOverlapWords, len(words), len(nextWords)); n >= minOverlapWords; n-- {
+		for shift := 0; shift <= maxOverlapShift && shift+n <= len(nextWords); shift++ {
+			if sameWords(words[len(words)-n:], nextWords[shift:shift+n]) {
+				return strings.Join(append(words, nextWords[shift+n:]...), " ")
+			}
+		}
+	}
+
+	return strings.TrimSpace(text + " " + next)
+}
+
+func sameWords(a, b []string) bool {
+	for i := range a {
+		if normalizeWord(a[i]) != normalizeWord(b[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func normalizeWord(word string) string {
+	return strings.ToLower(strings.TrimFunc(word, func(r rune) bool {
+		return unicode.IsPunct(r) || unicode.IsSymbol(r)
+	}))
+}
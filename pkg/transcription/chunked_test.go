@@ -0,0 +1,62 @@
+package transcription
+
+import "testing"
+
+func TestStitch(t *testing.T) {
+	tests := []struct {
+		name       string
+		text, next string
+		want       string
+	}{
+		{
+			name: "first segment",
+			text: "",
+			next: "hello there",
+			want: "hello there",
+		},
+		{
+			name: "overlap at the start",
+			text: "we agreed to ship on friday",
+			next: "ship on friday and then review",
+			want: "we agreed to ship on friday and then review",
+		},
+		{
+			name: "overlap at a shifted start",
+			text: "we agreed to ship on friday",
+			next: "ay ship on friday and then review",
+			want: "we agreed to ship on friday and then review",
+		},
+		{
+			name: "punctuation and case differ",
+			text: "See you tomorrow.",
+			next: "you, Tomorrow then we start",
+			want: "See you tomorrow. then we start",
+		},
+		{
+			name: "no overlap",
+			text: "first part",
+			next: "second part",
+			want: "first part second part",
+		},
+		{
+			name: "a single repeated word is not an overlap",
+			text: "this is the end",
+			next: "end of the story",
+			want: "this is the end end of the story",
+		},
+		{
+			name: "overlap too far from the start",
+			text: "one two three",
+			next: "a b c d two three four",
+			want: "one two three a b c d two three four",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := stitch(tt.text, tt.next); got != tt.want {
+				t.Errorf("stitch(%q, %q) = %q, want %q", tt.text, tt.next, got, tt.want)
+			}
+		})
+	}
+}
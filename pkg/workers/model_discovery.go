@@ -0,0 +1,89 @@
+package workers
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/dskvich/chatgpt-telegram-bot/pkg/llm"
+	"github.com/dskvich/chatgpt-telegram-bot/pkg/logger"
+)
+
+// refreshTimeout bounds a refresh, as the AI client has no overall timeout.
+const refreshTimeout = time.Minute
+
+type modelProvider interface {
+	llm.Provider
+	ListModels(ctx context.Context) ([]string, error)
+}
+
+type modelRegistry interface {
+	SetModels(provider llm.Provider, models ...string)
+}
+
+// modelDiscovery keeps the models of a provider in the registry in sync with the ones its API
+// offers. A failed or empty refresh keeps the current list, so the static fallback stays
+// in place until the API answers.
+type modelDiscovery struct {
+	provider modelProvider
+	registry modelRegistry
+	allow    []string
+	deny     []string
+	interval time.Duration
+}
+
+func NewModelDiscovery(
+	provider modelProvider,
+	registry modelRegistry,
+	allow, deny []string,
+	interval time.Duration,
+) (*modelDiscovery, error) {
+	return &modelDiscovery{
+		provider: provider,
+		registry: registry,
+		allow:    allow,
+		deny:     deny,
+		interval: interval,
+	}, nil
+}
+
+func (m *modelDiscovery) Name() string { return "model_discovery" }
+
+func (m *modelDiscovery) Start(ctx context.Context) error {
+	slog.Info("Starting worker", "name", m.Name())
+	defer slog.Info("Worker stopped", "name", m.Name())
+
+	ticker := time.NewTicker(m.interval)
+	defer ticker.Stop()
+
+	for {
+		m.refresh(ctx)
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+func (m *modelDiscovery) refresh(ctx context.Context) {
+	listCtx, cancel := context.WithTimeout(ctx, refreshTimeout)
+	defer cancel()
+
+	models, err := m.provider.ListModels(listCtx)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to list models", logger.Err(err))
+		return
+	}
+
+	models = llm.FilterModels(models, m.allow, m.deny)
+	if len(models) == 0 {
+		slog.WarnContext(ctx, "No models left after filtering, keeping the current list")
+		return
+	}
+
+	m.registry.SetModels(m.provider, models...)
+
+	slog.InfoContext(ctx, "Models refreshed", "count", len(models))
+}